@@ -2,8 +2,11 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -11,6 +14,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,7 +25,10 @@ import (
 	"github.com/shravanasati/shadowfax/server"
 )
 
-const port = 42069
+const (
+	port    = 42069
+	tlsPort = 42443
+)
 
 func headerAdder(next server.Handler) server.Handler {
 	return func(r *request.Request) response.Response {
@@ -31,16 +38,24 @@ func headerAdder(next server.Handler) server.Handler {
 	}
 }
 
+var usernameKey = request.NewKey[string]("username")
+
 func userOnly(next server.Handler) server.Handler {
 	return func(r *request.Request) response.Response {
-		if r.Headers.Get("username") != "user" {
+		username := r.Headers.Get("username")
+		if username != "user" {
 			return response.NewBaseResponse().WithStatusCode(response.StatusUnauthorized)
 		}
+		request.SetValue(r, usernameKey, username)
 		return next(r)
 	}
 }
 
 func main() {
+	devTLS := flag.Bool("tls", false, fmt.Sprintf("serve HTTPS on port %d with development certificates and redirect HTTP to it", tlsPort))
+	printRoutes := flag.Bool("routes", false, "print the route table and exit")
+	flag.Parse()
+
 	app := router.NewRouter(&router.RouterOptions{
 		EnableCors: true,
 		CorsOptions: router.CorsOptions{
@@ -60,7 +75,8 @@ func main() {
 	subRouter := router.NewRouter(nil)
 	subRouter.Use(userOnly)
 	subRouter.Get("/*", func(r *request.Request) response.Response {
-		return response.NewTextResponse("sub")
+		username, _ := request.GetValue(r, usernameKey)
+		return response.NewTextResponse("sub: " + username)
 	})
 
 	app.Handle("/sub", subRouter.Handler())
@@ -85,13 +101,14 @@ func main() {
 	})
 
 	app.Post("/httpbin/:x", func(r *request.Request) response.Response {
-		xs := r.PathParams["x"]
-		_, err := strconv.Atoi(xs)
+		params, err := request.Bind[struct {
+			Lines int `path:"x"`
+		}](r)
 		if err != nil {
-			return response.NewBaseResponse().WithStatusCode(response.StatusBadRequest)
+			return response.NewTextResponse(err.Error()).WithStatusCode(response.StatusBadRequest)
 		}
 
-		resp, err := http.Get("https://httpbin.org/stream/" + xs)
+		resp, err := http.Get("https://httpbin.org/stream/" + strconv.Itoa(params.Lines))
 		if err != nil {
 			fmt.Printf("Error fetching httpbin: %v\n", err)
 			return response.
@@ -141,13 +158,16 @@ func main() {
 			return response.NewBaseResponse().WithStatusCode(response.StatusBadRequest)
 		}
 
-		sr := response.NewStreamResponse(func(w io.Writer, setTrailer response.TrailerSetter) error {
+		ctx := r.Context()
+		sr := response.NewStreamResponseWithContext(ctx, func(w io.Writer, setTrailer response.TrailerSetter) error {
 			ticker := time.NewTicker(time.Millisecond * 100)
 			defer ticker.Stop()
 			deadline := time.After(time.Duration(s) * time.Second)
 
 			for {
 				select {
+				case <-ctx.Done():
+					return ctx.Err() // client went away
 				case <-deadline:
 					return nil // finishes after 2s, pipe closes
 				case t := <-ticker.C:
@@ -237,13 +257,22 @@ func main() {
 	})
 
 	app.Post("/upload", func(r *request.Request) response.Response {
-		body, err := r.Body()
+		mr, err := r.MultipartReader()
 		if err != nil {
 			return response.NewTextResponse(err.Error()).WithStatusCode(response.StatusBadRequest)
 		}
-		content, err := io.ReadAll(body)
-		fmt.Println(string(content), err)
-		return response.NewBaseResponse()
+		var received []string
+		for part, err := range mr.Parts() {
+			if err != nil {
+				return response.NewTextResponse(err.Error()).WithStatusCode(response.StatusBadRequest)
+			}
+			n, err := io.Copy(io.Discard, part)
+			if err != nil {
+				return response.NewTextResponse(err.Error()).WithStatusCode(response.StatusBadRequest)
+			}
+			received = append(received, fmt.Sprintf("%s (%s): %d bytes", part.FormName, part.FileName, n))
+		}
+		return response.NewTextResponse(strings.Join(received, "\n"))
 	})
 
 	app.Get("/file", func(r *request.Request) response.Response {
@@ -271,24 +300,63 @@ func main() {
 
 	app.Handle("/public/*file", middleware.NewStaticHandler("file", middleware.NewDirFS("./public")))
 
-	server, err := server.Serve(server.ServerOpts{
-		Address: ":42069",
+	if *printRoutes {
+		if err := app.WriteRoutes(os.Stdout); err != nil {
+			log.Fatalf("Error printing routes: %v", err)
+		}
+		return
+	}
+
+	opts := server.ServerOpts{
+		Address: fmt.Sprintf(":%d", port),
 		// Recovery: func(r any) response.Response {
 		// 	return response.NewTextResponse(fmt.Sprintf("sowwy I fucked up due to %v :<)", r))
 		// },
 		ReadTimeout:      30 * time.Second,
 		KeepAliveTimeout: 10 * time.Second,
 		// WriteTimeout: time.Second,
-	}, app.Handler())
+	}
 
+	var redirectServer *server.Server
+	if *devTLS {
+		certs, err := server.EnsureDevCertificates(".certs")
+		if err != nil {
+			log.Fatalf("Error creating development certificates: %v", err)
+		}
+		log.Println("Trust", certs.CACertFile, "to avoid certificate warnings")
+
+		opts.Address = fmt.Sprintf(":%d", tlsPort)
+		opts.TLS = &server.TLSOptions{Certificates: []server.CertificateFiles{certs.CertificateFiles}}
+
+		redirectServer = server.NewHTTPSRedirectServer(
+			server.ServerOpts{Address: fmt.Sprintf(":%d", port), DisableBanner: true},
+			server.HTTPSRedirectOptions{HTTPSPort: tlsPort},
+		)
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, server.ErrServerClosed) {
+				log.Println("Error starting redirect server:", err)
+			}
+		}()
+	}
+
+	server, err := server.Serve(opts, app.Handler())
 	if err != nil {
 		log.Fatalf("Error starting server: %v", err)
 	}
-	defer server.Close()
-	log.Println("Server started on port", port)
+	log.Println("Server started on", server.Addr())
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
-	log.Println("Server gracefully stopped")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stats, err := server.Shutdown(ctx)
+	if err != nil {
+		log.Println("Shutdown timed out:", err)
+	}
+	if redirectServer != nil {
+		redirectServer.Close()
+	}
+	log.Printf("Server gracefully stopped (%d connections drained, %d killed)", stats.Drained, stats.Killed)
 }
@@ -0,0 +1,191 @@
+// Package cookie implements HTTP cookies as specified by RFC 6265.
+package cookie
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SameSite is the value of the SameSite cookie attribute.
+type SameSite int
+
+const (
+	// SameSiteDefault leaves the attribute out, browsers then default to Lax.
+	SameSiteDefault SameSite = iota
+	SameSiteLax
+	SameSiteStrict
+	// SameSiteNone sends the cookie with cross-site requests too. It requires Secure.
+	SameSiteNone
+)
+
+func (s SameSite) String() string {
+	switch s {
+	case SameSiteLax:
+		return "Lax"
+	case SameSiteStrict:
+		return "Strict"
+	case SameSiteNone:
+		return "None"
+	default:
+		return ""
+	}
+}
+
+// timeFormat is the IMF-fixdate format used by the Expires attribute.
+const timeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// Cookie is an HTTP cookie. Cookies parsed from a request only have Name and Value set.
+type Cookie struct {
+	Name  string
+	Value string
+
+	// Path and Domain scope the cookie. Domain makes the cookie available to subdomains too.
+	Path   string
+	Domain string
+
+	// Expires is the absolute expiry of the cookie, ignored if zero.
+	Expires time.Time
+
+	// MaxAge is the lifetime of the cookie in seconds. Zero leaves the attribute out,
+	// a negative value deletes the cookie right away (Max-Age=0).
+	MaxAge int
+
+	// Secure restricts the cookie to HTTPS, HttpOnly hides it from JavaScript.
+	Secure   bool
+	HttpOnly bool
+
+	SameSite SameSite
+
+	// Partitioned stores the cookie per top-level site (CHIPS). It requires Secure.
+	Partitioned bool
+}
+
+// https://datatracker.ietf.org/doc/html/rfc9110#name-tokens
+var nameRegex = regexp.MustCompile(`^[a-zA-Z0-9!#$%&'*\+\-.^_\x60\|~]+$`)
+
+// https://datatracker.ietf.org/doc/html/rfc1034#section-3.5, with IP addresses
+var domainRegex = regexp.MustCompile(`^\.?[a-zA-Z0-9]([a-zA-Z0-9\-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9\-]*[a-zA-Z0-9])?)*$`)
+
+// validValueByte reports whether c is a cookie-octet as per RFC 6265 section 4.1.1:
+// US-ASCII characters excluding CTLs, whitespace, DQUOTE, comma, semicolon and backslash.
+func validValueByte(c byte) bool {
+	return 0x20 < c && c < 0x7f && c != '"' && c != ',' && c != ';' && c != '\\'
+}
+
+// validValue reports whether v is a valid cookie value, optionally wrapped in double quotes.
+func validValue(v string) bool {
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = v[1 : len(v)-1]
+	}
+	for i := 0; i < len(v); i++ {
+		if !validValueByte(v[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// validPath reports whether p is a valid Path attribute: any CHAR except CTLs or ";".
+func validPath(p string) bool {
+	for i := 0; i < len(p); i++ {
+		if c := p[i]; c < 0x20 || c >= 0x7f || c == ';' {
+			return false
+		}
+	}
+	return true
+}
+
+// Valid checks that the cookie can be safely sent in a Set-Cookie header.
+// Besides the syntax of the name, value, path and domain, it enforces the rules browsers
+// apply: SameSite=None and Partitioned require Secure, the `__Secure-` prefix requires
+// Secure, and the `__Host-` prefix requires Secure, Path=/ and no Domain.
+func (c *Cookie) Valid() error {
+	if !nameRegex.MatchString(c.Name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, c.Name)
+	}
+	if !validValue(c.Value) {
+		return fmt.Errorf("%w: %q", ErrInvalidValue, c.Value)
+	}
+	if !validPath(c.Path) {
+		return fmt.Errorf("%w: path %q", ErrInvalidAttribute, c.Path)
+	}
+	if c.Domain != "" && !domainRegex.MatchString(c.Domain) {
+		return fmt.Errorf("%w: domain %q", ErrInvalidAttribute, c.Domain)
+	}
+	if c.SameSite == SameSiteNone && !c.Secure {
+		return fmt.Errorf("%w: SameSite=None requires Secure", ErrInvalidAttribute)
+	}
+	if c.Partitioned && !c.Secure {
+		return fmt.Errorf("%w: Partitioned requires Secure", ErrInvalidAttribute)
+	}
+	if strings.HasPrefix(c.Name, "__Secure-") && !c.Secure {
+		return fmt.Errorf("%w: __Secure- prefix requires Secure", ErrInvalidAttribute)
+	}
+	if strings.HasPrefix(c.Name, "__Host-") && (!c.Secure || c.Path != "/" || c.Domain != "") {
+		return fmt.Errorf("%w: __Host- prefix requires Secure, Path=/ and no Domain", ErrInvalidAttribute)
+	}
+	return nil
+}
+
+// String returns the cookie serialized for a Set-Cookie header. It doesn't validate the
+// cookie, use [Cookie.Valid] for that.
+func (c *Cookie) String() string {
+	var b strings.Builder
+	b.WriteString(c.Name)
+	b.WriteByte('=')
+	b.WriteString(c.Value)
+
+	if c.Path != "" {
+		b.WriteString("; Path=")
+		b.WriteString(c.Path)
+	}
+	if c.Domain != "" {
+		b.WriteString("; Domain=")
+		b.WriteString(strings.TrimPrefix(c.Domain, "."))
+	}
+	if !c.Expires.IsZero() {
+		b.WriteString("; Expires=")
+		b.WriteString(c.Expires.UTC().Format(timeFormat))
+	}
+	if c.MaxAge > 0 {
+		b.WriteString("; Max-Age=")
+		b.WriteString(strconv.Itoa(c.MaxAge))
+	} else if c.MaxAge < 0 {
+		b.WriteString("; Max-Age=0")
+	}
+	if c.HttpOnly {
+		b.WriteString("; HttpOnly")
+	}
+	if c.Secure {
+		b.WriteString("; Secure")
+	}
+	if c.SameSite != SameSiteDefault {
+		b.WriteString("; SameSite=")
+		b.WriteString(c.SameSite.String())
+	}
+	if c.Partitioned {
+		b.WriteString("; Partitioned")
+	}
+	return b.String()
+}
+
+// Parse parses the value of a Cookie request header into cookies, in order.
+// Malformed pairs are skipped. Double quotes around values are removed.
+func Parse(header string) []*Cookie {
+	var cookies []*Cookie
+	for pair := range strings.SplitSeq(header, ";") {
+		pair = strings.TrimSpace(pair)
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || !nameRegex.MatchString(name) || !validValue(value) {
+			continue
+		}
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		cookies = append(cookies, &Cookie{Name: name, Value: value})
+	}
+	return cookies
+}
@@ -0,0 +1,91 @@
+package cookie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieString(t *testing.T) {
+	tests := []struct {
+		name     string
+		cookie   Cookie
+		expected string
+	}{
+		{"name and value", Cookie{Name: "id", Value: "abc"}, "id=abc"},
+		{"empty value", Cookie{Name: "id"}, "id="},
+		{
+			"all attributes",
+			Cookie{
+				Name: "session", Value: "xyz", Path: "/app", Domain: ".example.com",
+				Expires: time.Date(2026, 10, 21, 7, 28, 0, 0, time.FixedZone("IST", 19800)),
+				MaxAge:  3600, Secure: true, HttpOnly: true, SameSite: SameSiteNone, Partitioned: true,
+			},
+			"session=xyz; Path=/app; Domain=example.com; Expires=Wed, 21 Oct 2026 01:58:00 GMT; Max-Age=3600; HttpOnly; Secure; SameSite=None; Partitioned",
+		},
+		{"delete", Cookie{Name: "id", MaxAge: -1}, "id=; Max-Age=0"},
+		{"same site strict", Cookie{Name: "id", Value: "1", SameSite: SameSiteStrict}, "id=1; SameSite=Strict"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.cookie.Valid())
+			assert.Equal(t, tt.expected, tt.cookie.String())
+		})
+	}
+}
+
+func TestCookieValid(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie Cookie
+		err    error
+	}{
+		{"empty name", Cookie{Value: "v"}, ErrInvalidName},
+		{"name with space", Cookie{Name: "my id", Value: "v"}, ErrInvalidName},
+		{"name with equals", Cookie{Name: "a=b", Value: "v"}, ErrInvalidName},
+		{"value with semicolon", Cookie{Name: "id", Value: "a; Domain=evil.com"}, ErrInvalidValue},
+		{"value with CRLF", Cookie{Name: "id", Value: "a\r\nX-Injected: 1"}, ErrInvalidValue},
+		{"value with space", Cookie{Name: "id", Value: "a b"}, ErrInvalidValue},
+		{"path with semicolon", Cookie{Name: "id", Path: "/; Secure"}, ErrInvalidAttribute},
+		{"path with newline", Cookie{Name: "id", Path: "/\n"}, ErrInvalidAttribute},
+		{"bad domain", Cookie{Name: "id", Domain: "exa mple.com"}, ErrInvalidAttribute},
+		{"same site none without secure", Cookie{Name: "id", SameSite: SameSiteNone}, ErrInvalidAttribute},
+		{"partitioned without secure", Cookie{Name: "id", Partitioned: true}, ErrInvalidAttribute},
+		{"secure prefix without secure", Cookie{Name: "__Secure-id"}, ErrInvalidAttribute},
+		{"host prefix with domain", Cookie{Name: "__Host-id", Secure: true, Path: "/", Domain: "example.com"}, ErrInvalidAttribute},
+		{"host prefix without root path", Cookie{Name: "__Host-id", Secure: true, Path: "/app"}, ErrInvalidAttribute},
+		{"quoted value", Cookie{Name: "id", Value: `"quoted"`}, nil},
+		{"host prefix", Cookie{Name: "__Host-id", Secure: true, Path: "/"}, nil},
+		{"ip domain", Cookie{Name: "id", Domain: "127.0.0.1"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cookie.Valid()
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	cookies := Parse(`session=abc123; theme="dark"; bad name=x; novalue; empty=; lang=en`)
+
+	var pairs [][2]string
+	for _, c := range cookies {
+		pairs = append(pairs, [2]string{c.Name, c.Value})
+	}
+	assert.Equal(t, [][2]string{
+		{"session", "abc123"},
+		{"theme", "dark"},
+		{"empty", ""},
+		{"lang", "en"},
+	}, pairs)
+
+	assert.Empty(t, Parse(""))
+}
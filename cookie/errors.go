@@ -0,0 +1,12 @@
+package cookie
+
+import "errors"
+
+// ErrInvalidName is returned when a cookie name is not a valid token.
+var ErrInvalidName = errors.New("invalid cookie name")
+
+// ErrInvalidValue is returned when a cookie value contains characters not allowed by RFC 6265.
+var ErrInvalidValue = errors.New("invalid cookie value")
+
+// ErrInvalidAttribute is returned when a cookie attribute is invalid, or attributes contradict each other.
+var ErrInvalidAttribute = errors.New("invalid cookie attribute")
@@ -3,8 +3,8 @@ package headers
 import (
 	"bytes"
 	"iter"
-	"maps"
 	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -12,8 +12,20 @@ import (
 var fieldNameRegex = regexp.MustCompile(`^[a-zA-Z0-9!#$%&'*\+\-.^_\x60\|~]+$`)
 
 // Headers represents a collection of HTTP headers.
+// Each field keeps its values in the order they were added, and fields are iterated in
+// the order they were first added. Field names are case-insensitive.
 type Headers struct {
-	headers map[string]string
+	fields map[string]*field
+	order  []string
+
+	// preserveCase makes Fields yield names in the case they were first added with
+	preserveCase bool
+}
+
+// field is a header field with all its values.
+type field struct {
+	name   string
+	values []string
 }
 
 func isValidFieldName(key string) bool {
@@ -47,31 +59,71 @@ func NormalizeKey(key string) string {
 	return strings.ToLower(strings.TrimSpace(key))
 }
 
-// Add adds a new header. If the header already exists, the new value is appended to the existing value, separated by a comma.
+// Add adds a new header. If the header already exists, the value is added to the existing values.
+// [Headers.Get] returns all the values joined by a comma, [Headers.Values] returns them separately.
 func (h *Headers) Add(key, value string) {
 	if !isValidFieldName(key) || !isValidFieldValue([]byte(value)) {
 		// drop invalid headers to prevent response splitting
 		return
 	}
 
-	key = NormalizeKey(key)
-	if existing, ok := h.headers[key]; ok {
-		// multiple values
-		h.headers[key] = existing + ", " + value
-	} else {
-		h.headers[key] = value
+	if h.fields == nil {
+		h.fields = map[string]*field{}
+	}
+	normalized := NormalizeKey(key)
+	f, ok := h.fields[normalized]
+	if !ok {
+		f = &field{name: strings.TrimSpace(key)}
+		h.fields[normalized] = f
+		h.order = append(h.order, normalized)
+	}
+	f.values = append(f.values, value)
+}
+
+// AddMulti adds several values to a header, see [Headers.Add].
+func (h *Headers) AddMulti(key string, values ...string) {
+	for _, value := range values {
+		h.Add(key, value)
+	}
+}
+
+// AddAll adds every field of src, see [Headers.Add]. Multiple values, their order and the
+// case of the names are kept, src is left unchanged.
+func (h *Headers) AddAll(src *Headers) {
+	for _, key := range src.order {
+		f := src.fields[key]
+		h.AddMulti(f.name, f.values...)
 	}
 }
 
-// Get returns the value of a header.
+// Get returns the value of a header. Multiple values are joined by a comma.
 func (h *Headers) Get(key string) string {
-	key = NormalizeKey(key)
-	return h.headers[key]
+	f, ok := h.fields[NormalizeKey(key)]
+	if !ok {
+		return ""
+	}
+	return strings.Join(f.values, ", ")
+}
+
+// Values returns all the values of a header, in the order they were added.
+// Values must be used for fields that can't be combined into a single comma separated
+// value, such as Set-Cookie.
+func (h *Headers) Values(key string) []string {
+	f, ok := h.fields[NormalizeKey(key)]
+	if !ok {
+		return nil
+	}
+	return slices.Clone(f.values)
 }
 
 // Remove removes a header.
 func (h *Headers) Remove(key string) {
-	delete(h.headers, NormalizeKey(key))
+	key = NormalizeKey(key)
+	if _, ok := h.fields[key]; !ok {
+		return
+	}
+	delete(h.fields, key)
+	h.order = slices.DeleteFunc(h.order, func(k string) bool { return k == key })
 }
 
 // Set sets a header value, as opposed to Add which appends the value if it alredy exists.
@@ -81,13 +133,47 @@ func (h *Headers) Set(key, value string) {
 		return
 	}
 
-	key = NormalizeKey(key)
-	h.headers[key] = value
+	h.Remove(key)
+	h.Add(key, value)
 }
 
-// All returns an iterator over all headers.
+// All returns an iterator over all headers, in insertion order.
+// Keys are lowercased and multiple values are joined by a comma, as returned by [Headers.Get].
 func (h *Headers) All() iter.Seq2[string, string] {
-	return maps.All(h.headers)
+	return func(yield func(string, string) bool) {
+		for _, key := range h.order {
+			if !yield(key, strings.Join(h.fields[key].values, ", ")) {
+				return
+			}
+		}
+	}
+}
+
+// Fields returns an iterator over every field line, one per value, in insertion order.
+// This is how headers are written on the wire. Names are lowercased, unless
+// [Headers.SetPreserveCase] is enabled.
+func (h *Headers) Fields() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, key := range h.order {
+			f := h.fields[key]
+			name := key
+			if h.preserveCase {
+				name = f.name
+			}
+			for _, value := range f.values {
+				if !yield(name, value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SetPreserveCase sets whether [Headers.Fields] yields names in the case they were first
+// added with (e.g. `Content-Type`), instead of lowercase. Some legacy clients only
+// understand specific casings. Lookups are always case-insensitive.
+func (h *Headers) SetPreserveCase(preserve bool) {
+	h.preserveCase = preserve
 }
 
 // ParseFieldLine parses a single header line and adds it to the headers.
@@ -115,14 +201,14 @@ func (h *Headers) ParseFieldLine(data []byte) (err error) {
 	return nil
 }
 
-// Size returns the number of headers.
+// Size returns the number of distinct headers.
 func (h *Headers) Size() int {
-	return len(h.headers)
+	return len(h.order)
 }
 
 // NewHeaders creates a new Headers object.
 func NewHeaders() *Headers {
 	return &Headers{
-		headers: map[string]string{},
+		fields: map[string]*field{},
 	}
 }
@@ -224,3 +224,101 @@ func TestHeadersMethods(t *testing.T) {
 		})
 	})
 }
+
+func TestHeadersMultipleValues(t *testing.T) {
+	t.Run("Values and AddMulti", func(t *testing.T) {
+		headers := NewHeaders()
+		headers.Add("Set-Cookie", "a=1; Path=/")
+		headers.AddMulti("set-cookie", "b=2; Expires=Wed, 21 Oct 2026 07:28:00 GMT", "c=3")
+
+		assert.Equal(t, []string{"a=1; Path=/", "b=2; Expires=Wed, 21 Oct 2026 07:28:00 GMT", "c=3"}, headers.Values("Set-Cookie"))
+		assert.Equal(t, "a=1; Path=/, b=2; Expires=Wed, 21 Oct 2026 07:28:00 GMT, c=3", headers.Get("SET-COOKIE"))
+		assert.Equal(t, 1, headers.Size())
+		assert.Nil(t, headers.Values("Missing"))
+
+		// the returned slice is a copy
+		headers.Values("Set-Cookie")[0] = "changed"
+		assert.Equal(t, "a=1; Path=/", headers.Values("Set-Cookie")[0])
+
+		// Set replaces all values
+		headers.Set("Set-Cookie", "d=4")
+		assert.Equal(t, []string{"d=4"}, headers.Values("Set-Cookie"))
+
+		// invalid values are dropped
+		headers.AddMulti("X-Test", "ok", "bad\r\nvalue")
+		assert.Equal(t, []string{"ok"}, headers.Values("X-Test"))
+	})
+
+	t.Run("insertion order", func(t *testing.T) {
+		headers := NewHeaders()
+		names := []string{"x-first", "content-type", "a-last", "b", "www-authenticate"}
+		for _, name := range names {
+			headers.Add(name, "v")
+		}
+		headers.Add("content-type", "again")
+		headers.Remove("b")
+		headers.Add("b", "re-added")
+
+		var keys []string
+		for k := range headers.All() {
+			keys = append(keys, k)
+		}
+		assert.Equal(t, []string{"x-first", "content-type", "a-last", "www-authenticate", "b"}, keys)
+	})
+
+	t.Run("Fields", func(t *testing.T) {
+		headers := NewHeaders()
+		headers.Add("WWW-Authenticate", `Basic realm="site"`)
+		headers.Add("Content-Type", "text/plain")
+		headers.Add("www-authenticate", `Bearer realm="api", error="invalid_token"`)
+
+		type line struct{ name, value string }
+		var lines []line
+		for name, value := range headers.Fields() {
+			lines = append(lines, line{name, value})
+		}
+		assert.Equal(t, []line{
+			{"www-authenticate", `Basic realm="site"`},
+			{"www-authenticate", `Bearer realm="api", error="invalid_token"`},
+			{"content-type", "text/plain"},
+		}, lines)
+
+		// original case is kept as first added
+		headers.SetPreserveCase(true)
+		lines = nil
+		for name, value := range headers.Fields() {
+			lines = append(lines, line{name, value})
+		}
+		assert.Equal(t, "WWW-Authenticate", lines[0].name)
+		assert.Equal(t, "WWW-Authenticate", lines[1].name)
+		assert.Equal(t, "Content-Type", lines[2].name)
+		assert.Equal(t, "text/plain", headers.Get("content-type"))
+	})
+
+	t.Run("AddAll", func(t *testing.T) {
+		src := NewHeaders()
+		src.Add("Vary", "Origin")
+		src.Add("Access-Control-Allow-Origin", "*")
+		src.Add("vary", "Accept")
+
+		dst := NewHeaders()
+		dst.Add("Vary", "Accept-Encoding")
+		dst.SetPreserveCase(true)
+		dst.AddAll(src)
+
+		assert.Equal(t, []string{"Accept-Encoding", "Origin", "Accept"}, dst.Values("vary"))
+		var names []string
+		for name := range dst.Fields() {
+			names = append(names, name)
+		}
+		assert.Equal(t, []string{"Vary", "Vary", "Vary", "Access-Control-Allow-Origin"}, names)
+
+		assert.False(t, src.preserveCase, "src should be left unchanged")
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		var headers Headers
+		headers.Add("A", "1")
+		assert.Equal(t, "1", headers.Get("a"))
+	})
+}
@@ -15,6 +15,17 @@ type Account struct {
 	Password string
 }
 
+// basicAuthUserKey holds the username of the authenticated account.
+var basicAuthUserKey = request.NewKey[string]("basic-auth-user")
+
+// BasicAuthUser returns the username authenticated by [BasicAuthMiddleware] for this request.
+func BasicAuthUser(r *request.Request) (string, bool) {
+	return request.GetValue(r, basicAuthUserKey)
+}
+
+// BasicAuthMiddleware authenticates requests against the given accounts using HTTP Basic
+// authentication. The username of the authenticated account is available to downstream
+// handlers via [BasicAuthUser].
 func BasicAuthMiddleware(accounts []Account) router.Middleware {
 	accountMap := make(map[string]string)
 	for _, acc := range accounts {
@@ -52,6 +63,7 @@ func BasicAuthMiddleware(accounts []Account) router.Middleware {
 					WithStatusCode(response.StatusUnauthorized)
 			}
 
+			request.SetValue(r, basicAuthUserKey, user)
 			return next(r)
 		}
 	}
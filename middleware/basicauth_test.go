@@ -96,3 +96,28 @@ func TestBasicAuth_Success(t *testing.T) {
 		t.Fatalf("expected next handler to be called")
 	}
 }
+
+func TestBasicAuth_ExposesUser(t *testing.T) {
+	mw := BasicAuthMiddleware([]Account{{Username: "user", Password: "pass"}})
+	var gotUser string
+	var gotOk bool
+	handler := mw(func(r *request.Request) response.Response {
+		gotUser, gotOk = BasicAuthUser(r)
+		return response.NewBaseResponse()
+	})
+
+	req := newReqNoBody("GET", "/")
+	payload := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	req.Headers.Add("Authorization", "Basic "+payload)
+
+	handler(req)
+
+	if !gotOk || gotUser != "user" {
+		t.Fatalf("expected authenticated user %q, got %q (ok=%v)", "user", gotUser, gotOk)
+	}
+
+	// no user on unauthenticated requests
+	if _, ok := BasicAuthUser(newReqNoBody("GET", "/")); ok {
+		t.Fatalf("expected no authenticated user")
+	}
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"strings"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/shravanasati/shadowfax/router"
+	"github.com/shravanasati/shadowfax/server"
+)
+
+// ClientIdentity is the identity of a client authenticated with a TLS client certificate.
+type ClientIdentity struct {
+	// Certificate is the verified client certificate.
+	Certificate *x509.Certificate
+
+	// VerifiedChains are the chains from Certificate up to one of the trusted CAs.
+	VerifiedChains [][]*x509.Certificate
+}
+
+// CommonName returns the common name of the certificate subject.
+func (id *ClientIdentity) CommonName() string {
+	return id.Certificate.Subject.CommonName
+}
+
+// names returns all the names of the certificate as typed values (cn:, dns:, email:, uri:, ip:),
+// in the format patterns are matched against.
+func (id *ClientIdentity) names() []string {
+	cert := id.Certificate
+	names := []string{"cn:" + cert.Subject.CommonName}
+	for _, dns := range cert.DNSNames {
+		names = append(names, "dns:"+dns)
+	}
+	for _, email := range cert.EmailAddresses {
+		names = append(names, "email:"+email)
+	}
+	for _, uri := range cert.URIs {
+		names = append(names, "uri:"+uri.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		names = append(names, "ip:"+ip.String())
+	}
+	return names
+}
+
+// clientIdentityKey holds the identity of the client certificate.
+var clientIdentityKey = request.NewKey[*ClientIdentity]("client-cert-identity")
+
+// ClientCertIdentity returns the identity authenticated by [ClientCertAuth] for this request.
+func ClientCertIdentity(r *request.Request) (*ClientIdentity, bool) {
+	return request.GetValue(r, clientIdentityKey)
+}
+
+// ClientCertAuth authenticates requests with TLS client certificates (mutual TLS).
+// The server must ask clients for certificates, see server.TLSOptions.ClientAuth.
+// Use NewClientCertAuth and then ClientCertAuth.Handler or ClientCertAuth.Require.
+type ClientCertAuth struct {
+	roots *x509.CertPool
+}
+
+// NewClientCertAuth creates a ClientCertAuth that accepts client certificates issued by one
+// of the given CAs.
+func NewClientCertAuth(roots *x509.CertPool) *ClientCertAuth {
+	return &ClientCertAuth{roots: roots}
+}
+
+func clientCertUnauthorized() response.Response {
+	return response.NewTextResponse("client certificate required").
+		WithStatusCode(response.StatusUnauthorized)
+}
+
+func clientCertForbidden() response.Response {
+	return response.NewTextResponse("client certificate not allowed").
+		WithStatusCode(response.StatusForbidden)
+}
+
+// verify verifies the client certificate of the request against the trusted CAs.
+func (c *ClientCertAuth) verify(r *request.Request) (*ClientIdentity, bool) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, false
+	}
+
+	leaf := r.TLS.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         c.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, false
+	}
+	return &ClientIdentity{Certificate: leaf, VerifiedChains: chains}, true
+}
+
+// Handler returns a middleware-wrapped handler that requires a verified client certificate.
+// Requests without a certificate, or with one that isn't issued by a trusted CA, get a
+// 401 Unauthorized response.
+func (c *ClientCertAuth) Handler(next server.Handler) server.Handler {
+	return c.Require()(next)
+}
+
+// Require returns a middleware that requires a verified client certificate whose names
+// match at least one of the patterns. It's meant to be applied per route or per sub-router.
+//
+// Patterns may be prefixed with the kind of name they match: "cn:" for the subject common
+// name, "dns:", "email:", "uri:" and "ip:" for subject alternative names. Unprefixed patterns
+// match any kind of name. A "*" in a pattern matches any sequence of characters, for
+// example "dns:*.billing.internal" or "uri:spiffe://cluster/ns/*/sa/payments".
+//
+// Requests without a valid certificate get a 401 Unauthorized response, requests whose
+// certificate doesn't match any pattern get a 403 Forbidden response.
+// With no patterns, any verified certificate is accepted.
+func (c *ClientCertAuth) Require(patterns ...string) router.Middleware {
+	return func(next server.Handler) server.Handler {
+		return func(r *request.Request) response.Response {
+			id, ok := c.verify(r)
+			if !ok {
+				return clientCertUnauthorized()
+			}
+
+			if len(patterns) > 0 && !matchesAnyName(patterns, id.names()) {
+				return clientCertForbidden()
+			}
+
+			request.SetValue(r, clientIdentityKey, id)
+			return next(r)
+		}
+	}
+}
+
+var namePrefixes = []string{"cn:", "dns:", "email:", "uri:", "ip:"}
+
+func matchesAnyName(patterns []string, names []string) bool {
+	for _, pattern := range patterns {
+		typed := false
+		for _, prefix := range namePrefixes {
+			if strings.HasPrefix(pattern, prefix) {
+				typed = true
+				break
+			}
+		}
+
+		for _, name := range names {
+			value := name
+			if !typed {
+				// match the name without its kind
+				value = name[strings.IndexByte(name, ':')+1:]
+			}
+			if globMatch(pattern, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// globMatch reports whether s matches the pattern, where "*" matches any sequence of characters.
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
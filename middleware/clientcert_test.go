@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+func (ca *testCA) issue(t *testing.T, cn string, dnsNames []string, uris []string, usage x509.ExtKeyUsage) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var parsedURIs []*url.URL
+	for _, u := range uris {
+		pu, err := url.Parse(u)
+		require.NoError(t, err)
+		parsedURIs = append(parsedURIs, pu)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		URIs:         parsedURIs,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func newTLSReq(certs ...*x509.Certificate) *request.Request {
+	req := newReqNoBody("GET", "/")
+	req.TLS = &tls.ConnectionState{HandshakeComplete: true, PeerCertificates: certs}
+	return req
+}
+
+func TestClientCertAuth(t *testing.T) {
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+
+	billing := ca.issue(t, "billing", []string{"billing.svc.internal"}, []string{"spiffe://cluster/ns/prod/sa/billing"}, x509.ExtKeyUsageClientAuth)
+	untrusted := otherCA.issue(t, "billing", []string{"billing.svc.internal"}, nil, x509.ExtKeyUsageClientAuth)
+	serverOnly := ca.issue(t, "billing", []string{"billing.svc.internal"}, nil, x509.ExtKeyUsageServerAuth)
+
+	auth := NewClientCertAuth(ca.pool())
+	ok := func(_ *request.Request) response.Response { return response.NewBaseResponse() }
+
+	tests := []struct {
+		name     string
+		patterns []string
+		req      *request.Request
+		expected response.StatusCode
+	}{
+		{"no tls", nil, newReqNoBody("GET", "/"), response.StatusUnauthorized},
+		{"no certificate", nil, newTLSReq(), response.StatusUnauthorized},
+		{"untrusted ca", nil, newTLSReq(untrusted), response.StatusUnauthorized},
+		{"wrong key usage", nil, newTLSReq(serverOnly), response.StatusUnauthorized},
+		{"any verified certificate", nil, newTLSReq(billing), response.StatusOK},
+		{"common name", []string{"cn:billing"}, newTLSReq(billing), response.StatusOK},
+		{"dns wildcard", []string{"dns:*.svc.internal"}, newTLSReq(billing), response.StatusOK},
+		{"uri wildcard", []string{"uri:spiffe://cluster/ns/*/sa/billing"}, newTLSReq(billing), response.StatusOK},
+		{"untyped pattern", []string{"billing.svc.*"}, newTLSReq(billing), response.StatusOK},
+		{"one of many", []string{"cn:payments", "cn:billing"}, newTLSReq(billing), response.StatusOK},
+		{"no match", []string{"cn:payments", "dns:*.payments.internal"}, newTLSReq(billing), response.StatusForbidden},
+		{"kind mismatch", []string{"dns:billing"}, newTLSReq(billing), response.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := auth.Require(tc.patterns...)(ok)(tc.req)
+			assert.Equal(t, tc.expected, resp.GetStatusCode())
+		})
+	}
+}
+
+func TestClientCertAuth_ExposesIdentity(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, "billing", nil, nil, x509.ExtKeyUsageClientAuth)
+
+	var id *ClientIdentity
+	var found bool
+	handler := NewClientCertAuth(ca.pool()).Handler(func(r *request.Request) response.Response {
+		id, found = ClientCertIdentity(r)
+		return response.NewBaseResponse()
+	})
+
+	resp := handler(newTLSReq(cert))
+	require.Equal(t, response.StatusOK, resp.GetStatusCode())
+	require.True(t, found)
+	assert.Equal(t, "billing", id.CommonName())
+	assert.Equal(t, cert, id.Certificate)
+	require.Len(t, id.VerifiedChains, 1)
+	assert.Equal(t, ca.cert, id.VerifiedChains[0][1])
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		expected   bool
+	}{
+		{"abc", "abc", true},
+		{"abc", "abcd", false},
+		{"*", "", true},
+		{"*.example.com", "api.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"a*a", "a", false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, globMatch(tc.pattern, tc.s), "%q vs %q", tc.pattern, tc.s)
+	}
+}
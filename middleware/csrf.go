@@ -0,0 +1,284 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"slices"
+	"sync/atomic"
+
+	"github.com/shravanasati/shadowfax/cookie"
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/shravanasati/shadowfax/server"
+)
+
+// CSRFMode selects where [CSRF] keeps the expected token.
+type CSRFMode int
+
+const (
+	// CSRFDoubleSubmit keeps the token in a cookie, requests must send the same token back
+	// in a header or form field. It doesn't need server-side state.
+	CSRFDoubleSubmit CSRFMode = iota
+	// CSRFSessionBound keeps the token in the session, see [Sessions]. It requires the
+	// sessions middleware to run before the CSRF middleware, and is resistant to cookie
+	// injection from sibling subdomains.
+	CSRFSessionBound
+)
+
+const (
+	csrfTokenLen = 32
+
+	// csrfSessionKey is the session value holding the token in session-bound mode.
+	csrfSessionKey = "_csrf"
+)
+
+// CSRFOptions configures [CSRF]. Zero values select the defaults.
+type CSRFOptions struct {
+	Mode CSRFMode
+
+	// HeaderName is the request header carrying the token. Defaults to "X-CSRF-Token".
+	HeaderName string
+
+	// FieldName is the form field carrying the token in urlencoded and multipart bodies.
+	// Defaults to "csrf_token". Streaming uploads read with [request.Request.MultipartReader]
+	// must send the token in the header instead, since the field would consume the body.
+	FieldName string
+
+	// CookieName is the name of the token cookie in double-submit mode. Defaults to "csrf_token".
+	CookieName string
+
+	// Path, Domain, Secure and SameSite configure the token cookie in double-submit mode.
+	// Path defaults to "/" and SameSite to Lax.
+	Path     string
+	Domain   string
+	Secure   bool
+	SameSite cookie.SameSite
+}
+
+// CSRF protects unsafe requests with synchronizer tokens. It complements [CORF] for clients
+// that send neither Origin nor Sec-Fetch-Site. Use NewCSRF and CSRF.Handler, templates get
+// the token with [CSRFToken] or [CSRFTemplateFuncs].
+type CSRF struct {
+	opts CSRFOptions
+	deny atomic.Pointer[server.Handler] // if nil, falls back to defaultDenyHandler
+}
+
+// NewCSRF creates a CSRF middleware. Errors are returned when the cookie options are invalid.
+func NewCSRF(opts CSRFOptions) (*CSRF, error) {
+	if opts.HeaderName == "" {
+		opts.HeaderName = "X-CSRF-Token"
+	}
+	if opts.FieldName == "" {
+		opts.FieldName = "csrf_token"
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = "csrf_token"
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.SameSite == cookie.SameSiteDefault {
+		opts.SameSite = cookie.SameSiteLax
+	}
+
+	c := &CSRF{opts: opts}
+	if err := c.cookie("").Valid(); err != nil {
+		return nil, fmt.Errorf("invalid csrf cookie options: %w", err)
+	}
+	return c, nil
+}
+
+// SetDenyHandler sets the handler called for requests failing the CSRF check; pass nil to use
+// the default, which responds with 403 Forbidden. [CSRFFailureReason] tells why the request failed.
+func (c *CSRF) SetDenyHandler(h server.Handler) {
+	if h == nil {
+		var nilPtr *server.Handler
+		c.deny.Store(nilPtr)
+		return
+	}
+	c.deny.Store(&h)
+}
+
+func (c *CSRF) effectiveDeny() server.Handler {
+	if p := c.deny.Load(); p != nil {
+		return *p
+	}
+	return defaultDenyHandler
+}
+
+func (c *CSRF) cookie(value string) *cookie.Cookie {
+	return &cookie.Cookie{
+		Name:     c.opts.CookieName,
+		Value:    value,
+		Path:     c.opts.Path,
+		Domain:   c.opts.Domain,
+		Secure:   c.opts.Secure,
+		HttpOnly: true,
+		SameSite: c.opts.SameSite,
+	}
+}
+
+// csrfState is the token of a request, see [CSRFToken].
+type csrfState struct {
+	token     []byte
+	fieldName string
+}
+
+var (
+	csrfStateKey   = request.NewKey[*csrfState]("csrf")
+	csrfFailureKey = request.NewKey[error]("csrf-failure")
+)
+
+// CSRFToken returns the CSRF token to send back with unsafe requests, or an empty string if
+// the request didn't go through [CSRF.Handler]. The token is masked differently on every call,
+// so it can be embedded in compressed responses without leaking (BREACH).
+func CSRFToken(r *request.Request) string {
+	state, ok := request.GetValue(r, csrfStateKey)
+	if !ok {
+		return ""
+	}
+	return maskToken(state.token)
+}
+
+// CSRFTemplateFuncs returns template functions for the CSRF token of the request, to be used
+// with [response.NewTemplateResponseWithFuncs]:
+//
+//	{{ csrfToken }} renders the token, e.g. in a meta tag read by scripts.
+//	{{ csrfField }} renders a hidden input carrying the token, for forms.
+func CSRFTemplateFuncs(r *request.Request) template.FuncMap {
+	fieldName := "csrf_token"
+	if state, ok := request.GetValue(r, csrfStateKey); ok {
+		fieldName = state.fieldName
+	}
+	return template.FuncMap{
+		"csrfToken": func() string { return CSRFToken(r) },
+		"csrfField": func() template.HTML {
+			return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+				template.HTMLEscapeString(fieldName), CSRFToken(r)))
+		},
+	}
+}
+
+// CSRFFailureReason returns why the request failed the CSRF check, for deny handlers.
+// It is [ErrCSRFTokenMissing], [ErrCSRFTokenInvalid] or [ErrSessionNotFound].
+func CSRFFailureReason(r *request.Request) error {
+	err, _ := request.GetValue(r, csrfFailureKey)
+	return err
+}
+
+// maskToken returns base64(mask || mask^token), with a random mask.
+func maskToken(token []byte) string {
+	masked := make([]byte, 2*len(token))
+	rand.Read(masked[:len(token)])
+	subtle.XORBytes(masked[len(token):], masked[:len(token)], token)
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// unmaskToken reverses maskToken.
+func unmaskToken(s string) ([]byte, bool) {
+	masked, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(masked) != 2*csrfTokenLen {
+		return nil, false
+	}
+	token := make([]byte, csrfTokenLen)
+	subtle.XORBytes(token, masked[:csrfTokenLen], masked[csrfTokenLen:])
+	return token, true
+}
+
+func newCSRFToken() []byte {
+	token := make([]byte, csrfTokenLen)
+	rand.Read(token)
+	return token
+}
+
+func decodeCSRFToken(s string) ([]byte, bool) {
+	token, err := base64.RawURLEncoding.DecodeString(s)
+	return token, err == nil && len(token) == csrfTokenLen
+}
+
+// submittedToken returns the token sent with the request, from the header or else from the
+// form field of an urlencoded or multipart body.
+func (c *CSRF) submittedToken(r *request.Request) (string, error) {
+	if token := r.Headers.Get(c.opts.HeaderName); token != "" {
+		return token, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Headers.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" && mediaType != "multipart/form-data" {
+		return "", nil
+	}
+	form, err := r.PostForm()
+	if err != nil {
+		return "", err
+	}
+	return form.Get(c.opts.FieldName), nil
+}
+
+// Handler returns a middleware-wrapped handler that enforces CSRF tokens on unsafe methods.
+func (c *CSRF) Handler(next server.Handler) server.Handler {
+	return func(r *request.Request) response.Response {
+		var expected []byte
+		var sess *Session
+		if c.opts.Mode == CSRFSessionBound {
+			sess = GetSession(r)
+			if sess == nil {
+				log.Printf("csrf: session-bound tokens require the sessions middleware")
+				request.SetValue(r, csrfFailureKey, ErrSessionNotFound)
+				return c.effectiveDeny()(r)
+			}
+			if v, ok := sess.Get(csrfSessionKey); ok {
+				expected, _ = decodeCSRFToken(v)
+			}
+		} else if ck, err := r.Cookie(c.opts.CookieName); err == nil {
+			expected, _ = decodeCSRFToken(ck.Value)
+		}
+
+		if !slices.Contains(safeMethods, r.Method) {
+			if err := c.verify(r, expected); err != nil {
+				request.SetValue(r, csrfFailureKey, err)
+				return c.effectiveDeny()(r)
+			}
+		}
+
+		issued := expected == nil
+		if issued {
+			expected = newCSRFToken()
+			if sess != nil {
+				sess.Set(csrfSessionKey, base64.RawURLEncoding.EncodeToString(expected))
+			}
+		}
+		request.SetValue(r, csrfStateKey, &csrfState{token: expected, fieldName: c.opts.FieldName})
+
+		resp := next(r)
+		if issued && sess == nil {
+			if err := response.SetCookie(resp, c.cookie(base64.RawURLEncoding.EncodeToString(expected))); err != nil {
+				log.Printf("unable to set CSRF cookie: %v", err)
+			}
+		}
+		return resp
+	}
+}
+
+// verify checks the token sent with the request against the expected one.
+func (c *CSRF) verify(r *request.Request, expected []byte) error {
+	if expected == nil {
+		return ErrCSRFTokenMissing
+	}
+	submitted, err := c.submittedToken(r)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCSRFTokenMissing, err)
+	}
+	if submitted == "" {
+		return ErrCSRFTokenMissing
+	}
+	token, ok := unmaskToken(submitted)
+	if !ok || subtle.ConstantTimeCompare(token, expected) != 1 {
+		return ErrCSRFTokenInvalid
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// csrfBrowser keeps the cookies set by responses, like a browser.
+type csrfBrowser struct {
+	cookies map[string]string
+}
+
+func (b *csrfBrowser) do(h func(*request.Request) response.Response, req *request.Request) response.Response {
+	var pairs []string
+	for name, value := range b.cookies {
+		pairs = append(pairs, name+"="+value)
+	}
+	if len(pairs) > 0 {
+		req.Headers.Add("Cookie", strings.Join(pairs, "; "))
+	}
+	resp := h(req)
+	for _, sc := range resp.GetHeaders().Values("Set-Cookie") {
+		pair, _, _ := strings.Cut(sc, ";")
+		name, value, _ := strings.Cut(pair, "=")
+		b.cookies[name] = value
+	}
+	return resp
+}
+
+func newFormReq(form url.Values) *request.Request {
+	req := newReq("POST", "/")
+	req.Headers.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBody(io.NopCloser(strings.NewReader(form.Encode())))
+	return req
+}
+
+func TestCSRF(t *testing.T) {
+	sessions := newTestSessions(t, SessionOptions{})
+
+	for _, mode := range []CSRFMode{CSRFDoubleSubmit, CSRFSessionBound} {
+		csrf, err := NewCSRF(CSRFOptions{Mode: mode})
+		require.NoError(t, err)
+
+		var token string
+		h := csrf.Handler(func(r *request.Request) response.Response {
+			token = CSRFToken(r)
+			return okHandler(r)
+		})
+		if mode == CSRFSessionBound {
+			h = sessions.Handler(h)
+		}
+
+		browser := &csrfBrowser{cookies: map[string]string{}}
+		resp := browser.do(h, newReq("GET", "/form"))
+		require.Equal(t, response.StatusOK, resp.GetStatusCode())
+		require.NotEmpty(t, token)
+		first := token
+
+		// the token stays the same across requests, but is masked differently every time
+		browser.do(h, newReq("GET", "/form"))
+		assert.NotEqual(t, first, token)
+		a, _ := unmaskToken(first)
+		b, _ := unmaskToken(token)
+		assert.Equal(t, a, b)
+
+		post := newReq("POST", "/submit")
+		resp = browser.do(h, post)
+		assert.Equal(t, response.StatusForbidden, resp.GetStatusCode(), "missing token")
+		assert.ErrorIs(t, CSRFFailureReason(post), ErrCSRFTokenMissing)
+
+		post = newReq("POST", "/submit")
+		post.Headers.Add("X-CSRF-Token", maskToken(newCSRFToken()))
+		resp = browser.do(h, post)
+		assert.Equal(t, response.StatusForbidden, resp.GetStatusCode(), "wrong token")
+		assert.ErrorIs(t, CSRFFailureReason(post), ErrCSRFTokenInvalid)
+
+		post = newReq("POST", "/submit")
+		post.Headers.Add("X-CSRF-Token", first)
+		resp = browser.do(h, post)
+		assert.Equal(t, response.StatusOK, resp.GetStatusCode(), "header token")
+
+		var body string
+		resp = browser.do(csrfBodyReader(h, &body), newFormReq(url.Values{"csrf_token": {first}, "name": {"frodo"}}))
+		assert.Equal(t, response.StatusOK, resp.GetStatusCode(), "form token")
+		assert.Contains(t, body, "name=frodo", "body must be available to the handler")
+
+		// a token from another client is rejected
+		other := &csrfBrowser{cookies: map[string]string{}}
+		other.do(h, newReq("GET", "/form"))
+		post = newReq("POST", "/submit")
+		post.Headers.Add("X-CSRF-Token", first)
+		resp = other.do(h, post)
+		assert.Equal(t, response.StatusForbidden, resp.GetStatusCode(), "foreign token")
+	}
+}
+
+// csrfBodyReader wraps a CSRF-protected handler chain, recording the body seen by the handler.
+func csrfBodyReader(h func(*request.Request) response.Response, body *string) func(*request.Request) response.Response {
+	return func(r *request.Request) response.Response {
+		resp := h(r)
+		if resp.GetStatusCode() == response.StatusOK {
+			rc, err := r.Body()
+			if err == nil {
+				b, _ := io.ReadAll(rc)
+				*body = string(b)
+			}
+		}
+		return resp
+	}
+}
+
+func TestCSRFDoubleSubmitCookie(t *testing.T) {
+	csrf, err := NewCSRF(CSRFOptions{Secure: true})
+	require.NoError(t, err)
+	h := csrf.Handler(okHandler)
+
+	resp := h(newReq("GET", "/"))
+	setCookie := resp.GetHeaders().Get("Set-Cookie")
+	assert.True(t, strings.HasPrefix(setCookie, "csrf_token="))
+	assert.Contains(t, setCookie, "HttpOnly")
+	assert.Contains(t, setCookie, "Secure")
+
+	_, err = NewCSRF(CSRFOptions{CookieName: "bad name"})
+	assert.Error(t, err)
+}
+
+func TestCSRFSessionBoundWithoutSessions(t *testing.T) {
+	csrf, err := NewCSRF(CSRFOptions{Mode: CSRFSessionBound})
+	require.NoError(t, err)
+	req := newReq("GET", "/")
+	resp := csrf.Handler(okHandler)(req)
+	assert.Equal(t, response.StatusForbidden, resp.GetStatusCode())
+	assert.ErrorIs(t, CSRFFailureReason(req), ErrSessionNotFound)
+}
+
+func TestCSRFCustomDenyHandler(t *testing.T) {
+	csrf, err := NewCSRF(CSRFOptions{})
+	require.NoError(t, err)
+	csrf.SetDenyHandler(func(r *request.Request) response.Response {
+		return response.NewTextResponse(CSRFFailureReason(r).Error()).WithStatusCode(response.StatusBadRequest)
+	})
+
+	resp := csrf.Handler(okHandler)(newReq("DELETE", "/"))
+	assert.Equal(t, response.StatusBadRequest, resp.GetStatusCode())
+	body, _ := io.ReadAll(resp.GetBody())
+	assert.Equal(t, ErrCSRFTokenMissing.Error(), string(body))
+
+	csrf.SetDenyHandler(nil)
+	resp = csrf.Handler(okHandler)(newReq("DELETE", "/"))
+	assert.Equal(t, response.StatusForbidden, resp.GetStatusCode())
+}
+
+func TestCSRFTemplateFuncs(t *testing.T) {
+	csrf, err := NewCSRF(CSRFOptions{FieldName: "_token"})
+	require.NoError(t, err)
+
+	var rendered string
+	csrf.Handler(func(r *request.Request) response.Response {
+		resp, err := response.NewTemplateResponseWithFuncs(
+			`<form>{{ csrfField }}</form><meta content="{{ csrfToken }}">`, CSRFTemplateFuncs(r), nil)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		io.Copy(&buf, resp.GetBody())
+		rendered = buf.String()
+		return resp
+	})(newReq("GET", "/"))
+
+	assert.Contains(t, rendered, `<input type="hidden" name="_token" value="`)
+	assert.Contains(t, rendered, `<meta content="`)
+
+	// without the middleware the functions render empty tokens instead of failing
+	tmpl := template.Must(template.New("t").Funcs(CSRFTemplateFuncs(newReq("GET", "/"))).Parse(`{{ csrfToken }}`))
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, nil))
+	assert.Empty(t, buf.String())
+}
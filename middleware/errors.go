@@ -0,0 +1,21 @@
+package middleware
+
+import "errors"
+
+// ErrSessionNotFound is returned by a [SessionStore] when a session doesn't exist or has expired.
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrSessionTooLarge is returned when a client-side session doesn't fit in a cookie.
+var ErrSessionTooLarge = errors.New("session data too large for a cookie")
+
+// ErrInvalidSessionKey is returned by [NewSessions] when no keys are configured, or a key is too short.
+var ErrInvalidSessionKey = errors.New("session keys must be at least 32 bytes long")
+
+// errInvalidSessionCookie is returned when a session cookie can't be verified or decrypted.
+var errInvalidSessionCookie = errors.New("invalid session cookie")
+
+// ErrCSRFTokenMissing is the [CSRFFailureReason] when a request has no CSRF token, or no token was issued to the client.
+var ErrCSRFTokenMissing = errors.New("csrf token missing")
+
+// ErrCSRFTokenInvalid is the [CSRFFailureReason] when the CSRF token of a request doesn't match.
+var ErrCSRFTokenInvalid = errors.New("csrf token invalid")
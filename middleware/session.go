@@ -0,0 +1,409 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shravanasati/shadowfax/cookie"
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/shravanasati/shadowfax/server"
+)
+
+const (
+	defaultSessionCookieName = "session"
+	defaultIdleTimeout       = 30 * time.Minute
+	defaultAbsoluteTimeout   = 24 * time.Hour
+
+	// maxSessionCookieLen is the maximum length of a session cookie value. Browsers accept
+	// around 4096 bytes for the whole cookie, attributes included.
+	maxSessionCookieLen = 3800
+)
+
+// SessionOptions configures [Sessions].
+type SessionOptions struct {
+	// CookieName is the name of the session cookie. Defaults to "session".
+	CookieName string
+
+	// Store keeps the session data on the server, the cookie then only carries the signed
+	// session ID. If nil, the session data is stored in the cookie itself.
+	Store SessionStore
+
+	// Keys sign, and optionally encrypt, the session cookie. Each key must be at least
+	// 32 bytes long. Cookies are always written with the first key and accepted with any
+	// of them, so new keys are added at the front and old ones removed once they're unused.
+	Keys [][]byte
+
+	// Encrypt encrypts the session cookie with AES-GCM, so clients can't read it.
+	Encrypt bool
+
+	// IdleTimeout expires sessions that haven't been used for this long. Defaults to
+	// 30 minutes, a negative value disables it.
+	IdleTimeout time.Duration
+
+	// AbsoluteTimeout expires sessions this long after they were created, whatever their
+	// activity. Defaults to 24 hours, a negative value disables it.
+	AbsoluteTimeout time.Duration
+
+	// Path and Domain scope the session cookie. Path defaults to "/".
+	Path   string
+	Domain string
+
+	// Secure restricts the session cookie to HTTPS.
+	Secure bool
+
+	// SameSite of the session cookie. Defaults to Lax.
+	SameSite cookie.SameSite
+}
+
+// Sessions loads and saves sessions around handlers. Use NewSessions and Sessions.Handler,
+// handlers then access the session with [GetSession].
+type Sessions struct {
+	opts  SessionOptions
+	codec *sessionCodec
+}
+
+// NewSessions creates a Sessions middleware. It returns [ErrInvalidSessionKey] if no key is
+// configured or a key is too short.
+func NewSessions(opts SessionOptions) (*Sessions, error) {
+	if opts.CookieName == "" {
+		opts.CookieName = defaultSessionCookieName
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = defaultIdleTimeout
+	}
+	if opts.AbsoluteTimeout == 0 {
+		opts.AbsoluteTimeout = defaultAbsoluteTimeout
+	}
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.SameSite == cookie.SameSiteDefault {
+		opts.SameSite = cookie.SameSiteLax
+	}
+
+	probe := cookie.Cookie{
+		Name: opts.CookieName, Path: opts.Path, Domain: opts.Domain,
+		Secure: opts.Secure, HttpOnly: true, SameSite: opts.SameSite,
+	}
+	if err := probe.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid session cookie options: %w", err)
+	}
+
+	codec, err := newSessionCodec(opts.CookieName, opts.Keys, opts.Encrypt)
+	if err != nil {
+		return nil, err
+	}
+	return &Sessions{opts: opts, codec: codec}, nil
+}
+
+// Session is the session of a request. It is safe for concurrent use.
+type Session struct {
+	mu       sync.Mutex
+	id       string
+	data     *SessionData
+	isNew    bool
+	modified bool
+
+	// oldID is the ID the session was loaded with, if it was regenerated or destroyed since.
+	oldID string
+	// expireCookie deletes the session cookie, unless the session is saved again.
+	expireCookie bool
+}
+
+var sessionKey = request.NewKey[*Session]("session")
+
+// GetSession returns the session of the request, or nil if the request didn't go through
+// [Sessions.Handler].
+func GetSession(r *request.Request) *Session {
+	s, _ := request.GetValue(r, sessionKey)
+	return s
+}
+
+func newSessionID() string {
+	return rand.Text()
+}
+
+func newSessionData(now time.Time) *SessionData {
+	return &SessionData{Values: make(map[string]string), CreatedAt: now, LastSeen: now}
+}
+
+// ID returns the session ID. It changes with [Session.Regenerate] and [Session.Destroy].
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// IsNew reports whether the session was created by this request.
+func (s *Session) IsNew() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isNew
+}
+
+// CreatedAt returns the time the session was created.
+func (s *Session) CreatedAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.CreatedAt
+}
+
+// Get returns the value stored under key.
+func (s *Session) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data.Values[key]
+	return v, ok
+}
+
+// Set stores a value under key.
+func (s *Session) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Values == nil {
+		s.data.Values = make(map[string]string)
+	}
+	s.data.Values[key] = value
+	s.modified = true
+}
+
+// Delete removes the value stored under key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Values[key]; ok {
+		delete(s.data.Values, key)
+		s.modified = true
+	}
+}
+
+// Clear removes all values and flash messages, keeping the session ID.
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Values = make(map[string]string)
+	s.data.Flashes = nil
+	s.modified = true
+}
+
+// AddFlash adds a flash message, which is kept until it is read with [Session.Flashes].
+func (s *Session) AddFlash(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Flashes = append(s.data.Flashes, message)
+	s.modified = true
+}
+
+// Flashes returns the flash messages and removes them from the session.
+func (s *Session) Flashes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flashes := s.data.Flashes
+	if len(flashes) > 0 {
+		s.data.Flashes = nil
+		s.modified = true
+	}
+	return flashes
+}
+
+// Regenerate gives the session a new ID and restarts its absolute timeout, keeping its data.
+// Call it whenever the privilege level changes, typically on login, to prevent session
+// fixation. The old session is deleted from the store.
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.isNew && s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = newSessionID()
+	s.data.CreatedAt = time.Now()
+	s.modified = true
+}
+
+// Destroy deletes the session and its cookie, typically on logout. Values set afterwards
+// go to a new, empty session.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.isNew && s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = newSessionID()
+	s.data = newSessionData(time.Now())
+	s.isNew = true
+	s.modified = false
+	s.expireCookie = true
+}
+
+// clientSession is the content of a client-side session cookie.
+type clientSession struct {
+	ID   string       `json:"id"`
+	Data *SessionData `json:"data"`
+}
+
+// expired reports whether the session data has reached its idle or absolute timeout.
+func (ss *Sessions) expired(data *SessionData, now time.Time) bool {
+	if ss.opts.IdleTimeout > 0 && now.After(data.LastSeen.Add(ss.opts.IdleTimeout)) {
+		return true
+	}
+	if ss.opts.AbsoluteTimeout > 0 && now.After(data.CreatedAt.Add(ss.opts.AbsoluteTimeout)) {
+		return true
+	}
+	return false
+}
+
+// ttl returns how long the session stays valid if it's saved now, or zero if it never expires.
+func (ss *Sessions) ttl(data *SessionData, now time.Time) time.Duration {
+	var ttl time.Duration
+	if ss.opts.IdleTimeout > 0 {
+		ttl = ss.opts.IdleTimeout
+	}
+	if ss.opts.AbsoluteTimeout > 0 {
+		remaining := data.CreatedAt.Add(ss.opts.AbsoluteTimeout).Sub(now)
+		if ttl == 0 || remaining < ttl {
+			ttl = max(remaining, time.Second)
+		}
+	}
+	return ttl
+}
+
+// load returns the session of the request. It also reports whether the cookie was signed
+// with an older key.
+func (ss *Sessions) load(r *request.Request, now time.Time) (*Session, bool) {
+	c, err := r.Cookie(ss.opts.CookieName)
+	if err != nil {
+		return ss.newSession(now), false
+	}
+	plain, rotated, err := ss.codec.decode(c.Value)
+	if err != nil {
+		return ss.newSession(now), false
+	}
+
+	var id string
+	var data *SessionData
+	if ss.opts.Store == nil {
+		var cs clientSession
+		if err := json.Unmarshal(plain, &cs); err != nil || cs.Data == nil || cs.ID == "" {
+			return ss.newSession(now), false
+		}
+		id, data = cs.ID, cs.Data
+	} else {
+		id = string(plain)
+		data, err = ss.opts.Store.Load(id)
+		if err != nil {
+			if !errors.Is(err, ErrSessionNotFound) {
+				log.Printf("unable to load session: %v", err)
+			}
+			return ss.newSession(now), false
+		}
+	}
+
+	if ss.expired(data, now) {
+		s := ss.newSession(now)
+		s.oldID = id
+		return s, false
+	}
+	return &Session{id: id, data: data}, rotated
+}
+
+func (ss *Sessions) newSession(now time.Time) *Session {
+	return &Session{id: newSessionID(), data: newSessionData(now), isNew: true}
+}
+
+// sessionCookie returns the session cookie with the given value.
+func (ss *Sessions) sessionCookie(value string) *cookie.Cookie {
+	return &cookie.Cookie{
+		Name:     ss.opts.CookieName,
+		Value:    value,
+		Path:     ss.opts.Path,
+		Domain:   ss.opts.Domain,
+		Secure:   ss.opts.Secure,
+		HttpOnly: true,
+		SameSite: ss.opts.SameSite,
+	}
+}
+
+// save persists the session and returns its cookie.
+func (ss *Sessions) save(s *Session, now time.Time) (*cookie.Cookie, error) {
+	s.data.LastSeen = now
+	ttl := ss.ttl(s.data, now)
+
+	var plain []byte
+	if ss.opts.Store == nil {
+		var err error
+		plain, err = json.Marshal(clientSession{ID: s.id, Data: s.data})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err := ss.opts.Store.Save(s.id, s.data, ttl); err != nil {
+			return nil, err
+		}
+		plain = []byte(s.id)
+	}
+
+	value, err := ss.codec.encode(plain)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) > maxSessionCookieLen {
+		return nil, ErrSessionTooLarge
+	}
+
+	c := ss.sessionCookie(value)
+	if ttl > 0 {
+		c.Expires = now.Add(ttl)
+	}
+	return c, nil
+}
+
+// Handler returns a middleware-wrapped handler that loads the session before calling next,
+// and saves it afterwards if it changed.
+func (ss *Sessions) Handler(next server.Handler) server.Handler {
+	return func(r *request.Request) response.Response {
+		now := time.Now()
+		s, rotated := ss.load(r, now)
+		request.SetValue(r, sessionKey, s)
+
+		resp := next(r)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		now = time.Now()
+
+		if s.oldID != "" && ss.opts.Store != nil {
+			if err := ss.opts.Store.Delete(s.oldID); err != nil {
+				log.Printf("unable to delete session: %v", err)
+			}
+		}
+
+		// refresh the idle timeout once a quarter of it has passed, rather than on every request
+		touch := !s.isNew && ss.opts.IdleTimeout > 0 && now.Sub(s.data.LastSeen) > ss.opts.IdleTimeout/4
+		if s.modified || rotated || touch {
+			c, err := ss.save(s, now)
+			if err != nil {
+				log.Printf("unable to save session: %v", err)
+				return resp
+			}
+			if err := response.SetCookie(resp, c); err != nil {
+				log.Printf("unable to set session cookie: %v", err)
+			}
+			return resp
+		}
+
+		if s.expireCookie || s.oldID != "" {
+			c := ss.sessionCookie("")
+			c.MaxAge = -1
+			if err := response.SetCookie(resp, c); err != nil {
+				log.Printf("unable to expire session cookie: %v", err)
+			}
+		}
+		return resp
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// minSessionKeyLen is the minimum length of session keys.
+const minSessionKeyLen = 32
+
+// codecKey holds the signing and encryption keys derived from a session key.
+type codecKey struct {
+	mac  []byte
+	aead cipher.AEAD
+}
+
+// sessionCodec signs, and optionally encrypts, session cookie values.
+// Values are encoded with the first key and decoded with any key, to allow key rotation.
+type sessionCodec struct {
+	name    string
+	keys    []codecKey
+	encrypt bool
+}
+
+// deriveKey derives a key for the given purpose, so the same secret is never used for
+// both signing and encryption.
+func deriveKey(secret []byte, purpose string) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(purpose))
+	return m.Sum(nil)
+}
+
+func newSessionCodec(name string, secrets [][]byte, encrypt bool) (*sessionCodec, error) {
+	if len(secrets) == 0 {
+		return nil, ErrInvalidSessionKey
+	}
+
+	c := &sessionCodec{name: name, encrypt: encrypt}
+	for _, secret := range secrets {
+		if len(secret) < minSessionKeyLen {
+			return nil, ErrInvalidSessionKey
+		}
+		block, err := aes.NewCipher(deriveKey(secret, "shadowfax session encryption"))
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c.keys = append(c.keys, codecKey{mac: deriveKey(secret, "shadowfax session signing"), aead: aead})
+	}
+	return c, nil
+}
+
+// sign computes the signature of the payload, bound to the cookie name.
+func (c *sessionCodec) sign(k codecKey, payload string) []byte {
+	m := hmac.New(sha256.New, k.mac)
+	m.Write([]byte(c.name))
+	m.Write([]byte{0})
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
+
+// encode returns the signed, and optionally encrypted, cookie value for plain.
+func (c *sessionCodec) encode(plain []byte) (string, error) {
+	k := c.keys[0]
+	data := plain
+	if c.encrypt {
+		nonce := make([]byte, k.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		data = k.aead.Seal(nonce, nonce, plain, []byte(c.name))
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(c.sign(k, payload)), nil
+}
+
+// decode verifies and decrypts a cookie value. It reports whether the value was encoded with
+// an older key, in which case it should be encoded again.
+func (c *sessionCodec) decode(value string) ([]byte, bool, error) {
+	payload, sigStr, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false, errInvalidSessionCookie
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigStr)
+	if err != nil {
+		return nil, false, errInvalidSessionCookie
+	}
+
+	for i, k := range c.keys {
+		if !hmac.Equal(sig, c.sign(k, payload)) {
+			continue
+		}
+		data, err := base64.RawURLEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, false, errInvalidSessionCookie
+		}
+		if c.encrypt {
+			nonceSize := k.aead.NonceSize()
+			if len(data) < nonceSize {
+				return nil, false, errInvalidSessionCookie
+			}
+			data, err = k.aead.Open(nil, data[:nonceSize], data[nonceSize:], []byte(c.name))
+			if err != nil {
+				return nil, false, errInvalidSessionCookie
+			}
+		}
+		return data, i > 0, nil
+	}
+	return nil, false, errInvalidSessionCookie
+}
@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SessionData is the data of a session, as persisted by a [SessionStore].
+type SessionData struct {
+	Values    map[string]string `json:"values,omitempty"`
+	Flashes   []string          `json:"flashes,omitempty"`
+	CreatedAt time.Time         `json:"created"`
+	LastSeen  time.Time         `json:"seen"`
+}
+
+func (d *SessionData) clone() *SessionData {
+	c := *d
+	c.Values = maps.Clone(d.Values)
+	c.Flashes = slices.Clone(d.Flashes)
+	return &c
+}
+
+// SessionStore stores session data on the server, keyed by session ID.
+// The session cookie then only carries the signed session ID.
+// Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// Load returns the data of a session, or [ErrSessionNotFound] if it doesn't exist or expired.
+	Load(id string) (*SessionData, error)
+
+	// Save creates or replaces the data of a session. The session expires after ttl,
+	// or never if ttl is zero.
+	Save(id string, data *SessionData, ttl time.Duration) error
+
+	// Delete deletes a session. Deleting a session that doesn't exist isn't an error.
+	Delete(id string) error
+}
+
+type memoryEntry struct {
+	data    *SessionData
+	expires time.Time
+}
+
+// memorySweepInterval is the number of saves between two sweeps of expired sessions.
+const memorySweepInterval = 100
+
+// MemoryStore is a [SessionStore] keeping sessions in memory. Sessions are lost when the
+// process exits, and aren't shared between processes.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memoryEntry
+	saves    int
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memoryEntry)}
+}
+
+func expiresAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func expired(expires time.Time) bool {
+	return !expires.IsZero() && time.Now().After(expires)
+}
+
+// Load implements [SessionStore].
+func (m *MemoryStore) Load(id string) (*SessionData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.sessions[id]
+	if !ok || expired(entry.expires) {
+		delete(m.sessions, id)
+		return nil, ErrSessionNotFound
+	}
+	return entry.data.clone(), nil
+}
+
+// Save implements [SessionStore].
+func (m *MemoryStore) Save(id string, data *SessionData, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[id] = memoryEntry{data: data.clone(), expires: expiresAt(ttl)}
+
+	m.saves++
+	if m.saves%memorySweepInterval == 0 {
+		for id, entry := range m.sessions {
+			if expired(entry.expires) {
+				delete(m.sessions, id)
+			}
+		}
+	}
+	return nil
+}
+
+// Delete implements [SessionStore].
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// Len returns the number of sessions in the store, including expired ones not swept yet.
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions)
+}
+
+// sessionIDRegex matches session IDs, which are also used as file names.
+var sessionIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// fileEntry is the content of a session file.
+type fileEntry struct {
+	Data    *SessionData `json:"data"`
+	Expires time.Time    `json:"expires,omitzero"`
+}
+
+// FileStore is a [SessionStore] keeping each session in a JSON file in a directory.
+// Sessions survive restarts and can be shared by processes on the same machine.
+// Expired files are deleted when loaded, call [FileStore.DeleteExpired] periodically to
+// delete the ones that are never loaded again.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore in dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) path(id string) (string, error) {
+	if !sessionIDRegex.MatchString(id) {
+		return "", fmt.Errorf("invalid session id %q", id)
+	}
+	return filepath.Join(f.dir, id+".json"), nil
+}
+
+func readFileEntry(path string) (*fileEntry, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry fileEntry
+	if err := json.Unmarshal(content, &entry); err != nil || entry.Data == nil {
+		return nil, fmt.Errorf("corrupted session file %s", path)
+	}
+	return &entry, nil
+}
+
+// Load implements [SessionStore].
+func (f *FileStore) Load(id string) (*SessionData, error) {
+	path, err := f.path(id)
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+	entry, err := readFileEntry(path)
+	if err != nil {
+		return nil, err
+	}
+	if expired(entry.Expires) {
+		os.Remove(path)
+		return nil, ErrSessionNotFound
+	}
+	return entry.Data, nil
+}
+
+// Save implements [SessionStore]. Files are replaced atomically.
+func (f *FileStore) Save(id string, data *SessionData, ttl time.Duration) error {
+	path, err := f.path(id)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(fileEntry{Data: data, Expires: expiresAt(ttl)})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(f.dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete implements [SessionStore].
+func (f *FileStore) Delete(id string) error {
+	path, err := f.path(id)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired deletes the files of all expired sessions.
+func (f *FileStore) DeleteExpired() error {
+	paths, err := filepath.Glob(filepath.Join(f.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, path := range paths {
+		entry, err := readFileEntry(path)
+		if errors.Is(err, ErrSessionNotFound) {
+			continue
+		}
+		if err != nil || expired(entry.Expires) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
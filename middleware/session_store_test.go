@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSessionStore(t *testing.T, store SessionStore) {
+	_, err := store.Load("missing")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	data := &SessionData{Values: map[string]string{"user": "frodo"}, Flashes: []string{"hi"}, CreatedAt: time.Now()}
+	require.NoError(t, store.Save("abc", data, time.Hour))
+
+	loaded, err := store.Load("abc")
+	require.NoError(t, err)
+	assert.Equal(t, data.Values, loaded.Values)
+	assert.Equal(t, data.Flashes, loaded.Flashes)
+
+	loaded.Values["user"] = "sam"
+	again, err := store.Load("abc")
+	require.NoError(t, err)
+	assert.Equal(t, "frodo", again.Values["user"], "loaded data must be a copy")
+
+	require.NoError(t, store.Save("short", data, time.Nanosecond))
+	time.Sleep(time.Millisecond)
+	_, err = store.Load("short")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	require.NoError(t, store.Delete("abc"))
+	require.NoError(t, store.Delete("abc"))
+	_, err = store.Load("abc")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testSessionStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	testSessionStore(t, store)
+}
+
+func TestFileStoreInvalidID(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(filepath.Join(dir, "sessions"))
+	require.NoError(t, err)
+
+	assert.Error(t, store.Save("../escape", &SessionData{}, 0))
+	_, err = store.Load("../escape")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = os.Stat(filepath.Join(dir, "escape.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFileStoreDeleteExpired(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Save("live", &SessionData{}, time.Hour))
+	require.NoError(t, store.Save("forever", &SessionData{}, 0))
+	require.NoError(t, store.Save("dead", &SessionData{}, time.Nanosecond))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("{"), 0o600))
+	time.Sleep(time.Millisecond)
+
+	require.NoError(t, store.DeleteExpired())
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(dir, "live.json"), filepath.Join(dir, "forever.json")}, files)
+}
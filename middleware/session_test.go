@@ -0,0 +1,307 @@
+package middleware
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	sessionKey1 = bytes.Repeat([]byte("k"), 32)
+	sessionKey2 = bytes.Repeat([]byte("n"), 32)
+)
+
+// sessionClient sends requests through a handler, keeping the session cookie like a browser.
+type sessionClient struct {
+	cookie string
+}
+
+func (c *sessionClient) do(h func(*request.Request) response.Response) response.Response {
+	req := newReq("GET", "/")
+	if c.cookie != "" {
+		req.Headers.Add("Cookie", c.cookie)
+	}
+	resp := h(req)
+	for _, sc := range resp.GetHeaders().Values("Set-Cookie") {
+		pair, _, _ := strings.Cut(sc, ";")
+		if strings.Contains(sc, "Max-Age=0") {
+			c.cookie = ""
+		} else {
+			c.cookie = pair
+		}
+	}
+	return resp
+}
+
+func newTestSessions(t *testing.T, opts SessionOptions) *Sessions {
+	t.Helper()
+	if opts.Keys == nil {
+		opts.Keys = [][]byte{sessionKey1}
+	}
+	ss, err := NewSessions(opts)
+	require.NoError(t, err)
+	return ss
+}
+
+func TestNewSessionsInvalidKeys(t *testing.T) {
+	_, err := NewSessions(SessionOptions{})
+	assert.ErrorIs(t, err, ErrInvalidSessionKey)
+	_, err = NewSessions(SessionOptions{Keys: [][]byte{[]byte("short")}})
+	assert.ErrorIs(t, err, ErrInvalidSessionKey)
+	_, err = NewSessions(SessionOptions{Keys: [][]byte{sessionKey1}, CookieName: "__Host-session"})
+	assert.Error(t, err)
+}
+
+func TestSessionsRoundTrip(t *testing.T) {
+	stores := map[string]func(t *testing.T) SessionStore{
+		"client":           func(t *testing.T) SessionStore { return nil },
+		"client encrypted": func(t *testing.T) SessionStore { return nil },
+		"memory":           func(t *testing.T) SessionStore { return NewMemoryStore() },
+		"file": func(t *testing.T) SessionStore {
+			fs, err := NewFileStore(t.TempDir())
+			require.NoError(t, err)
+			return fs
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ss := newTestSessions(t, SessionOptions{Store: newStore(t), Encrypt: name == "client encrypted"})
+			client := &sessionClient{}
+
+			resp := client.do(ss.Handler(func(r *request.Request) response.Response {
+				s := GetSession(r)
+				assert.True(t, s.IsNew())
+				s.Set("user", "gandalf")
+				return okHandler(r)
+			}))
+			setCookie := resp.GetHeaders().Get("Set-Cookie")
+			assert.Contains(t, setCookie, "HttpOnly")
+			assert.Contains(t, setCookie, "SameSite=Lax")
+			assert.Contains(t, setCookie, "Path=/")
+			if name == "client encrypted" {
+				assert.NotContains(t, setCookie, "gandalf")
+			}
+
+			var id string
+			resp = client.do(ss.Handler(func(r *request.Request) response.Response {
+				s := GetSession(r)
+				assert.False(t, s.IsNew())
+				user, ok := s.Get("user")
+				assert.True(t, ok)
+				assert.Equal(t, "gandalf", user)
+				id = s.ID()
+				return okHandler(r)
+			}))
+			assert.Empty(t, resp.GetHeaders().Values("Set-Cookie"), "unmodified session must not be saved")
+			assert.NotEmpty(t, id)
+		})
+	}
+}
+
+func TestSessionsRejectTampering(t *testing.T) {
+	ss := newTestSessions(t, SessionOptions{})
+	client := &sessionClient{}
+	client.do(ss.Handler(func(r *request.Request) response.Response {
+		GetSession(r).Set("role", "user")
+		return okHandler(r)
+	}))
+
+	name, value, _ := strings.Cut(client.cookie, "=")
+	payload, sig, _ := strings.Cut(value, ".")
+	client.cookie = name + "=" + payload + "x." + sig
+
+	client.do(ss.Handler(func(r *request.Request) response.Response {
+		s := GetSession(r)
+		assert.True(t, s.IsNew())
+		_, ok := s.Get("role")
+		assert.False(t, ok)
+		return okHandler(r)
+	}))
+}
+
+func TestSessionsKeyRotation(t *testing.T) {
+	for _, encrypt := range []bool{false, true} {
+		old := newTestSessions(t, SessionOptions{Encrypt: encrypt})
+		client := &sessionClient{}
+		client.do(old.Handler(func(r *request.Request) response.Response {
+			GetSession(r).Set("user", "frodo")
+			return okHandler(r)
+		}))
+		oldCookie := client.cookie
+
+		rotated := newTestSessions(t, SessionOptions{Encrypt: encrypt, Keys: [][]byte{sessionKey2, sessionKey1}})
+		resp := client.do(rotated.Handler(func(r *request.Request) response.Response {
+			user, _ := GetSession(r).Get("user")
+			assert.Equal(t, "frodo", user)
+			return okHandler(r)
+		}))
+		assert.Len(t, resp.GetHeaders().Values("Set-Cookie"), 1, "cookie must be re-signed with the new key")
+		assert.NotEqual(t, oldCookie, client.cookie)
+
+		onlyNew := newTestSessions(t, SessionOptions{Encrypt: encrypt, Keys: [][]byte{sessionKey2}})
+		client.do(onlyNew.Handler(func(r *request.Request) response.Response {
+			user, _ := GetSession(r).Get("user")
+			assert.Equal(t, "frodo", user)
+			return okHandler(r)
+		}))
+	}
+}
+
+func TestSessionsExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    SessionOptions
+		created time.Duration
+		seen    time.Duration
+		expired bool
+	}{
+		{"active", SessionOptions{}, -time.Hour, -time.Minute, false},
+		{"idle", SessionOptions{}, -time.Hour, -time.Hour, true},
+		{"absolute", SessionOptions{}, -25 * time.Hour, -time.Minute, true},
+		{"idle disabled", SessionOptions{IdleTimeout: -1}, -time.Hour, -time.Hour, false},
+		{"absolute disabled", SessionOptions{AbsoluteTimeout: -1}, -48 * time.Hour, -time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			tt.opts.Store = store
+			ss := newTestSessions(t, tt.opts)
+
+			now := time.Now()
+			require.NoError(t, store.Save("existing", &SessionData{
+				Values:    map[string]string{"user": "sam"},
+				CreatedAt: now.Add(tt.created),
+				LastSeen:  now.Add(tt.seen),
+			}, 0))
+			value, err := ss.codec.encode([]byte("existing"))
+			require.NoError(t, err)
+
+			client := &sessionClient{cookie: "session=" + value}
+			client.do(ss.Handler(func(r *request.Request) response.Response {
+				_, ok := GetSession(r).Get("user")
+				assert.Equal(t, !tt.expired, ok)
+				return okHandler(r)
+			}))
+
+			_, err = store.Load("existing")
+			if tt.expired {
+				assert.ErrorIs(t, err, ErrSessionNotFound)
+				assert.Empty(t, client.cookie)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSessionsIdleRefresh(t *testing.T) {
+	store := NewMemoryStore()
+	ss := newTestSessions(t, SessionOptions{Store: store})
+	require.NoError(t, store.Save("existing", &SessionData{
+		CreatedAt: time.Now().Add(-time.Hour),
+		LastSeen:  time.Now().Add(-20 * time.Minute),
+	}, 0))
+	value, err := ss.codec.encode([]byte("existing"))
+	require.NoError(t, err)
+
+	client := &sessionClient{cookie: "session=" + value}
+	resp := client.do(ss.Handler(okHandler))
+	assert.Len(t, resp.GetHeaders().Values("Set-Cookie"), 1)
+
+	data, err := store.Load("existing")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), data.LastSeen, time.Second)
+}
+
+func TestSessionRegenerate(t *testing.T) {
+	store := NewMemoryStore()
+	ss := newTestSessions(t, SessionOptions{Store: store})
+	client := &sessionClient{}
+
+	var before, after string
+	client.do(ss.Handler(func(r *request.Request) response.Response {
+		s := GetSession(r)
+		s.Set("cart", "ring")
+		before = s.ID()
+		return okHandler(r)
+	}))
+	client.do(ss.Handler(func(r *request.Request) response.Response {
+		s := GetSession(r)
+		s.Regenerate()
+		s.Set("user", "frodo")
+		after = s.ID()
+		return okHandler(r)
+	}))
+
+	assert.NotEqual(t, before, after)
+	_, err := store.Load(before)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	data, err := store.Load(after)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"cart": "ring", "user": "frodo"}, data.Values)
+}
+
+func TestSessionDestroy(t *testing.T) {
+	store := NewMemoryStore()
+	ss := newTestSessions(t, SessionOptions{Store: store})
+	client := &sessionClient{}
+
+	var id string
+	client.do(ss.Handler(func(r *request.Request) response.Response {
+		GetSession(r).Set("user", "frodo")
+		id = GetSession(r).ID()
+		return okHandler(r)
+	}))
+	resp := client.do(ss.Handler(func(r *request.Request) response.Response {
+		GetSession(r).Destroy()
+		return okHandler(r)
+	}))
+
+	assert.Contains(t, resp.GetHeaders().Get("Set-Cookie"), "Max-Age=0")
+	assert.Empty(t, client.cookie)
+	assert.Equal(t, 0, store.Len())
+	_, err := store.Load(id)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestSessionFlashes(t *testing.T) {
+	ss := newTestSessions(t, SessionOptions{})
+	client := &sessionClient{}
+
+	client.do(ss.Handler(func(r *request.Request) response.Response {
+		GetSession(r).AddFlash("saved")
+		GetSession(r).AddFlash("welcome back")
+		return okHandler(r)
+	}))
+	client.do(ss.Handler(func(r *request.Request) response.Response {
+		assert.Equal(t, []string{"saved", "welcome back"}, GetSession(r).Flashes())
+		return okHandler(r)
+	}))
+	client.do(ss.Handler(func(r *request.Request) response.Response {
+		assert.Empty(t, GetSession(r).Flashes())
+		return okHandler(r)
+	}))
+}
+
+func TestSessionTooLarge(t *testing.T) {
+	ss := newTestSessions(t, SessionOptions{})
+	client := &sessionClient{}
+	resp := client.do(ss.Handler(func(r *request.Request) response.Response {
+		GetSession(r).Set("blob", strings.Repeat("x", 5000))
+		return okHandler(r)
+	}))
+	assert.Equal(t, response.StatusOK, resp.GetStatusCode())
+	assert.Empty(t, resp.GetHeaders().Values("Set-Cookie"))
+}
+
+func TestGetSessionWithoutMiddleware(t *testing.T) {
+	assert.Nil(t, GetSession(newReq("GET", "/")))
+}
@@ -0,0 +1,352 @@
+package request
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bindSources are the struct tags read by [Request.DecodeParams], in order of precedence.
+var bindSources = []string{"path", "query", "header", "cookie", "form"}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	timeType            = reflect.TypeFor[time.Time]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// FieldError is a struct field that couldn't be bound, see [BindError].
+type FieldError struct {
+	// Field is the Go name of the field, dotted for nested structs.
+	Field string
+	// Source is the tag the value came from, e.g. "query", and Name the key in that source.
+	Source string
+	Name   string
+	// Value is the raw value, multiple values are joined with commas.
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %q: %v", e.Source, e.Name, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// BindError aggregates every field that couldn't be bound by [Request.DecodeParams].
+type BindError struct {
+	Errors []*FieldError
+}
+
+func (e *BindError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("%d invalid parameters: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *BindError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, fe := range e.Errors {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// binder fills a struct from the request.
+type binder struct {
+	r    *Request
+	errs []*FieldError
+	// err is an error of the request itself, such as an unparsable form, or of the target
+	err error
+	// bound counts the fields with a value in the request
+	bound int
+	// binding are the struct types being bound, to stop at recursive pointers
+	binding []reflect.Type
+}
+
+// DecodeParams fills the struct pointed to by v from the request, based on struct tags naming
+// the source and key of each field:
+//
+//	type ListParams struct {
+//		UserID int           `path:"id"`
+//		Page   *int          `query:"page"`              // nil when absent
+//		Tags   []string      `query:"tag"`               // every ?tag= value
+//		Since  time.Time     `query:"since" layout:"2006-01-02"`
+//		Wait   time.Duration `header:"X-Wait"`
+//		Theme  string        `cookie:"theme"`
+//		Name   string        `form:"name"`               // urlencoded or multipart body
+//	}
+//
+// Supported types are strings, bools, ints, uints, floats, [time.Duration], [time.Time] (RFC 3339
+// unless a layout tag is given), [encoding.TextUnmarshaler] implementations, and slices and
+// pointers of those. Absent values leave fields untouched, so pointers stay nil. Embedded and
+// untagged struct fields are bound recursively, as are pointers to structs, which are only
+// allocated when one of their fields has a value.
+//
+// Every conversion failure is reported in a single [*BindError]. Tagged fields of other types
+// are reported as [ErrInvalidBindTarget] whatever the request, and errors reading the form are
+// returned as is.
+func (r *Request) DecodeParams(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T", ErrInvalidBindTarget, v)
+	}
+
+	b := &binder{r: r}
+	b.bindStruct(rv.Elem(), "")
+	if b.err != nil {
+		return b.err
+	}
+	if len(b.errs) > 0 {
+		return &BindError{Errors: b.errs}
+	}
+	return nil
+}
+
+// Bind fills a new T from the request, see [Request.DecodeParams].
+func Bind[T any](r *Request) (T, error) {
+	var v T
+	err := r.DecodeParams(&v)
+	return v, err
+}
+
+// isBindLeaf reports whether values of type t are bound from a single value rather than
+// recursively.
+func isBindLeaf(t reflect.Type) bool {
+	return t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// isBindable reports whether a field of type t can be bound, see [Request.DecodeParams].
+func isBindable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice && !isBindLeaf(t) {
+		t = t.Elem()
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+	if t == durationType || isBindLeaf(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func (b *binder) bindStruct(v reflect.Value, prefix string) {
+	t := v.Type()
+	b.binding = append(b.binding, t)
+	defer func() { b.binding = b.binding[:len(b.binding)-1] }()
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+
+		source, name := "", ""
+		for _, s := range bindSources {
+			if tag, ok := f.Tag.Lookup(s); ok {
+				source, name = s, tag
+				break
+			}
+		}
+		if source == "" {
+			fieldPrefix := prefix + f.Name + "."
+			if f.Anonymous {
+				fieldPrefix = prefix
+			}
+			b.bindNested(fv, fieldPrefix)
+			if b.err != nil {
+				return
+			}
+			continue
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if !isBindable(f.Type) {
+			b.err = fmt.Errorf("%w: field %s%s has unsupported type %s", ErrInvalidBindTarget, prefix, f.Name, f.Type)
+			return
+		}
+
+		values, err := b.lookup(source, name)
+		if err != nil {
+			b.err = err
+			return
+		}
+		if len(values) == 0 {
+			continue
+		}
+		b.bound++
+		if err := setField(fv, values, f.Tag.Get("layout")); err != nil {
+			b.errs = append(b.errs, &FieldError{
+				Field:  prefix + f.Name,
+				Source: source,
+				Name:   name,
+				Value:  strings.Join(values, ","),
+				Err:    err,
+			})
+		}
+	}
+}
+
+// bindNested binds an untagged struct or pointer to struct field. Nil pointers are only
+// allocated when one of the fields has a value.
+func (b *binder) bindNested(v reflect.Value, prefix string) {
+	t := v.Type()
+	switch {
+	case t.Kind() == reflect.Struct && !isBindLeaf(t):
+		b.bindStruct(v, prefix)
+	case t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct && !isBindLeaf(t.Elem()):
+		if slices.Contains(b.binding, t.Elem()) {
+			return
+		}
+		if !v.IsNil() {
+			b.bindStruct(v.Elem(), prefix)
+			return
+		}
+		p := reflect.New(t.Elem())
+		bound, errs := b.bound, len(b.errs)
+		b.bindStruct(p.Elem(), prefix)
+		if b.bound > bound || len(b.errs) > errs {
+			v.Set(p)
+		}
+	}
+}
+
+// lookup returns the values of key in the given source.
+func (b *binder) lookup(source, key string) ([]string, error) {
+	switch source {
+	case "path":
+		if v, ok := b.r.PathParams[key]; ok {
+			return []string{v}, nil
+		}
+	case "query":
+		return b.r.Query[key], nil
+	case "header":
+		return b.r.Headers.Values(key), nil
+	case "cookie":
+		if c, err := b.r.Cookie(key); err == nil {
+			return []string{c.Value}, nil
+		}
+	case "form":
+		form, err := b.r.PostForm()
+		if err != nil {
+			return nil, err
+		}
+		return form[key], nil
+	}
+	return nil, nil
+}
+
+// setField converts values into v. Slices get every value, other types the first one.
+func setField(v reflect.Value, values []string, layout string) error {
+	t := v.Type()
+	if t.Kind() == reflect.Pointer {
+		p := reflect.New(t.Elem())
+		if err := setField(p.Elem(), values, layout); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	if t.Kind() == reflect.Slice && !isBindLeaf(t) {
+		s := reflect.MakeSlice(t, len(values), len(values))
+		for i, value := range values {
+			if err := setField(s.Index(i), []string{value}, layout); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	return setValue(v, values[0], layout)
+}
+
+// setValue converts a single value into v.
+func setValue(v reflect.Value, s, layout string) error {
+	t := v.Type()
+	invalid := func() error {
+		return fmt.Errorf("%w: %q is not a valid %s", ErrInvalidParam, s, t)
+	}
+
+	switch t {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return invalid()
+		}
+		v.SetInt(int64(d))
+		return nil
+	case timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		tm, err := time.Parse(layout, s)
+		if err != nil {
+			return fmt.Errorf("%w: %q doesn't match the time layout %q", ErrInvalidParam, s, layout)
+		}
+		v.Set(reflect.ValueOf(tm))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidParam, err)
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return invalid()
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return invalid()
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return invalid()
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return invalid()
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("%w: unsupported type %s", ErrInvalidBindTarget, t)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package request
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Paging struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+}
+
+type testParams struct {
+	Paging
+	UserID   int64         `path:"id"`
+	Verbose  bool          `query:"verbose"`
+	Ratio    float64       `query:"ratio"`
+	Tags     []string      `query:"tag"`
+	IDs      []uint16      `query:"ids"`
+	Optional *int          `query:"optional"`
+	Missing  *string       `query:"missing"`
+	Since    time.Time     `query:"since" layout:"2006-01-02"`
+	At       time.Time     `query:"at"`
+	Timeout  time.Duration `header:"X-Timeout"`
+	Accept   []string      `header:"Accept"`
+	Theme    string        `cookie:"theme"`
+	Addr     netip.Addr    `query:"addr"`
+	Skipped  string        `query:"-"`
+	Filter   struct {
+		Q string `query:"q"`
+	}
+	internal string `query:"internal"`
+}
+
+func TestDecodeParams(t *testing.T) {
+	r := parseTestRequest(t, "GET /users/42?page=2&limit=50&verbose=true&ratio=0.5&tag=a&tag=b&ids=1&ids=2&optional=7"+
+		"&since=2026-10-16&at=2026-10-16T10:00:00Z&addr=10.0.0.1&-=x&q=hobbit&internal=x HTTP/1.1\r\n"+
+		"Host: x\r\nX-Timeout: 1m30s\r\nAccept: text/html\r\nAccept: application/json\r\nCookie: theme=dark\r\n\r\n", nil)
+	r.PathParams = map[string]string{"id": "42"}
+
+	params, err := Bind[testParams](r)
+	require.NoError(t, err)
+
+	seven := 7
+	assert.Equal(t, testParams{
+		Paging:   Paging{Page: 2, Limit: 50},
+		UserID:   42,
+		Verbose:  true,
+		Ratio:    0.5,
+		Tags:     []string{"a", "b"},
+		IDs:      []uint16{1, 2},
+		Optional: &seven,
+		Since:    time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		At:       time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+		Timeout:  90 * time.Second,
+		Accept:   []string{"text/html", "application/json"},
+		Theme:    "dark",
+		Addr:     netip.MustParseAddr("10.0.0.1"),
+		Filter: struct {
+			Q string `query:"q"`
+		}{Q: "hobbit"},
+	}, params)
+}
+
+func TestDecodeParamsAggregatesErrors(t *testing.T) {
+	r := parseTestRequest(t, "GET /?page=two&verbose=maybe&ids=1&ids=70000&since=yesterday&addr=nope HTTP/1.1\r\nHost: x\r\nX-Timeout: soon\r\n\r\n", nil)
+	r.PathParams = map[string]string{"id": "abc"}
+
+	_, err := Bind[testParams](r)
+	var bindErr *BindError
+	require.ErrorAs(t, err, &bindErr)
+	assert.ErrorIs(t, err, ErrInvalidParam)
+
+	var fields []string
+	for _, fe := range bindErr.Errors {
+		fields = append(fields, fe.Field+"="+fe.Value)
+	}
+	assert.Equal(t, []string{
+		"Page=two", "UserID=abc", "Verbose=maybe", "IDs=1,70000", "Since=yesterday", "Timeout=soon", "Addr=nope",
+	}, fields)
+	assert.Equal(t, "query", bindErr.Errors[0].Source)
+	assert.Equal(t, "page", bindErr.Errors[0].Name)
+	assert.Contains(t, err.Error(), `7 invalid parameters: query "page": invalid parameter: "two" is not a valid int`)
+}
+
+func TestDecodeParamsForm(t *testing.T) {
+	type signup struct {
+		Name  string `form:"name"`
+		Age   int    `form:"age"`
+		Email string `form:""`
+	}
+	r := parseTestRequest(t, "POST / HTTP/1.1\r\nHost: x\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 36\r\n\r\nname=sam&age=38&Email=sam%40shire.me", nil)
+	s, err := Bind[signup](r)
+	require.NoError(t, err)
+	assert.Equal(t, signup{Name: "sam", Age: 38, Email: "sam@shire.me"}, s)
+
+	r = parseTestRequest(t, "POST / HTTP/1.1\r\nHost: x\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n{}", nil)
+	_, err = Bind[signup](r)
+	assert.ErrorIs(t, err, ErrUnsupportedFormType)
+}
+
+func TestDecodeParamsInvalidTarget(t *testing.T) {
+	r := parseTestRequest(t, "GET /?c=1 HTTP/1.1\r\nHost: x\r\n\r\n", nil)
+
+	var n int
+	assert.ErrorIs(t, r.DecodeParams(&n), ErrInvalidBindTarget)
+	assert.ErrorIs(t, r.DecodeParams(testParams{}), ErrInvalidBindTarget)
+
+	var unsupported struct {
+		Nested struct {
+			C chan int `query:"c"`
+		}
+	}
+	err := r.DecodeParams(&unsupported)
+	assert.ErrorIs(t, err, ErrInvalidBindTarget)
+	assert.False(t, errors.As(err, new(*BindError)))
+	assert.True(t, strings.Contains(err.Error(), "Nested.C has unsupported type chan int"))
+
+	// whether or not the request has a value
+	var absent struct {
+		M map[string]string `query:"m"`
+	}
+	assert.ErrorIs(t, r.DecodeParams(&absent), ErrInvalidBindTarget)
+}
+
+func TestDecodeParamsStructPointers(t *testing.T) {
+	type filter struct {
+		Q    string `query:"q"`
+		Next *filter
+	}
+	type params struct {
+		*Paging
+		Filter *filter
+	}
+
+	r := parseTestRequest(t, "GET /?q=hobbit HTTP/1.1\r\nHost: x\r\n\r\n", nil)
+	p, err := Bind[params](r)
+	require.NoError(t, err)
+	assert.Nil(t, p.Paging)
+	require.NotNil(t, p.Filter)
+	assert.Equal(t, "hobbit", p.Filter.Q)
+	assert.Nil(t, p.Filter.Next)
+
+	r = parseTestRequest(t, "GET /?page=2 HTTP/1.1\r\nHost: x\r\n\r\n", nil)
+	p, err = Bind[params](r)
+	require.NoError(t, err)
+	assert.Equal(t, &Paging{Page: 2}, p.Paging)
+	assert.Nil(t, p.Filter)
+
+	r = parseTestRequest(t, "GET /?limit=ten HTTP/1.1\r\nHost: x\r\n\r\n", nil)
+	_, err = Bind[params](r)
+	var bindErr *BindError
+	require.ErrorAs(t, err, &bindErr)
+	assert.Equal(t, "Limit", bindErr.Errors[0].Field)
+}
@@ -0,0 +1,68 @@
+package request
+
+import "context"
+
+// Context returns the request's context.
+// For requests served by the shadowfax server, the context is cancelled when the client
+// disconnects, the connection fails, the write deadline expires, the response has
+// been written, or the server is closed.
+// For requests not created by the server, it defaults to [context.Background].
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
+
+// SetContext replaces the request's context. It panics if ctx is nil.
+func (r *Request) SetContext(ctx context.Context) {
+	if ctx == nil {
+		panic("request: nil context")
+	}
+	r.ctx = ctx
+}
+
+// Key is a typed key for values stored on a request, see [SetValue] and [GetValue].
+// Keys are compared by identity, so two keys created with the same name never collide.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey creates a new key for values of type T. The name is only used for debugging.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// String returns the name of the key.
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+// SetValue attaches a value to the request under the given key, replacing any previous value.
+// Values are stored on the request context, so they are visible to everything downstream
+// of the caller (middleware, handlers and anything that receives [Request.Context]).
+func SetValue[T any](r *Request, key *Key[T], value T) {
+	r.SetContext(context.WithValue(r.Context(), key, value))
+}
+
+// GetValue returns the value stored on the request under the given key.
+// The boolean is false if no value has been set.
+func GetValue[T any](r *Request, key *Key[T]) (T, bool) {
+	return ValueFromContext(r.Context(), key)
+}
+
+// ValueFromContext is like [GetValue] but reads from a context, typically one obtained
+// from [Request.Context] and passed further down the call stack.
+func ValueFromContext[T any](ctx context.Context, key *Key[T]) (T, bool) {
+	v, ok := ctx.Value(key).(T)
+	return v, ok
+}
+
+// MustGetValue is like [GetValue] but panics if no value has been set.
+func MustGetValue[T any](r *Request, key *Key[T]) T {
+	v, ok := GetValue(r, key)
+	if !ok {
+		panic("request: no value for key " + key.name)
+	}
+	return v
+}
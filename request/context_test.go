@@ -0,0 +1,67 @@
+package request
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestContext(t *testing.T) {
+	t.Run("defaults to background", func(t *testing.T) {
+		r := &Request{}
+		assert.Equal(t, context.Background(), r.Context())
+	})
+
+	t.Run("set context", func(t *testing.T) {
+		r := &Request{}
+		ctx, cancel := context.WithCancel(context.Background())
+		r.SetContext(ctx)
+		cancel()
+		assert.ErrorIs(t, r.Context().Err(), context.Canceled)
+	})
+
+	t.Run("nil context panics", func(t *testing.T) {
+		r := &Request{}
+		assert.Panics(t, func() { r.SetContext(nil) })
+	})
+}
+
+func TestRequestValues(t *testing.T) {
+	userKey := NewKey[string]("user")
+	idKey := NewKey[int]("id")
+	otherUserKey := NewKey[string]("user")
+
+	r := &Request{}
+
+	_, ok := GetValue(r, userKey)
+	assert.False(t, ok)
+
+	SetValue(r, userKey, "gandalf")
+	SetValue(r, idKey, 42)
+
+	user, ok := GetValue(r, userKey)
+	require.True(t, ok)
+	assert.Equal(t, "gandalf", user)
+
+	id, ok := GetValue(r, idKey)
+	require.True(t, ok)
+	assert.Equal(t, 42, id)
+
+	// keys with the same name don't collide
+	_, ok = GetValue(r, otherUserKey)
+	assert.False(t, ok)
+
+	// values can be overwritten
+	SetValue(r, userKey, "frodo")
+	assert.Equal(t, "frodo", MustGetValue(r, userKey))
+
+	// values are visible through the context
+	user, ok = ValueFromContext(r.Context(), userKey)
+	require.True(t, ok)
+	assert.Equal(t, "frodo", user)
+
+	assert.Panics(t, func() { MustGetValue(r, otherUserKey) })
+	assert.Equal(t, "user", userKey.String())
+}
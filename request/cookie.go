@@ -0,0 +1,23 @@
+package request
+
+import "github.com/shravanasati/shadowfax/cookie"
+
+// Cookies parses and returns the cookies sent with the request, in order.
+// Malformed cookies are skipped.
+func (r *Request) Cookies() []*cookie.Cookie {
+	var cookies []*cookie.Cookie
+	for _, header := range r.Headers.Values("cookie") {
+		cookies = append(cookies, cookie.Parse(header)...)
+	}
+	return cookies
+}
+
+// Cookie returns the first cookie with the given name, or [ErrNoCookie].
+func (r *Request) Cookie(name string) (*cookie.Cookie, error) {
+	for _, c := range r.Cookies() {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return nil, ErrNoCookie
+}
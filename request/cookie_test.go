@@ -0,0 +1,25 @@
+package request
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestCookies(t *testing.T) {
+	r, err := RequestFromReader(strings.NewReader("GET / HTTP/1.1\r\nHost: x\r\nCookie: a=1; b=2\r\nCookie: c=3; a=4\r\n\r\n"), nil)
+	require.NoError(t, err)
+
+	cookies := r.Cookies()
+	require.Len(t, cookies, 4)
+	assert.Equal(t, "c", cookies[2].Name)
+
+	c, err := r.Cookie("a")
+	require.NoError(t, err)
+	assert.Equal(t, "1", c.Value)
+
+	_, err = r.Cookie("missing")
+	assert.ErrorIs(t, err, ErrNoCookie)
+}
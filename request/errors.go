@@ -34,3 +34,46 @@ var ErrChunkTooLarge = errors.New("chunk size exceeded configured limits")
 
 // ErrBodyTooLarge is returned when the body exceeds configured limits.
 var ErrBodyTooLarge = errors.New("body exceeded configured limits")
+
+// ErrNoCookie is returned by [Request.Cookie] when the cookie isn't found.
+var ErrNoCookie = errors.New("named cookie not present")
+
+// ErrUnsupportedFormType is returned by [Request.PostForm] when the body isn't urlencoded.
+var ErrUnsupportedFormType = errors.New("unsupported form content type")
+
+// ErrMalformedForm is returned by [Request.PostForm] when the body can't be decoded.
+var ErrMalformedForm = errors.New("malformed form body")
+
+// ErrMalformedMultipart is returned when a multipart body can't be parsed.
+var ErrMalformedMultipart = errors.New("malformed multipart body")
+
+// ErrTooManyParts is returned when a multipart body has more parts than allowed.
+var ErrTooManyParts = errors.New("multipart body has too many parts")
+
+// ErrPartTooLarge is returned when a multipart field or file exceeds its size limit.
+var ErrPartTooLarge = errors.New("multipart part exceeded configured limits")
+
+// ErrMultipartConsumed is returned when a multipart body was already handed to a multipart parser.
+var ErrMultipartConsumed = errors.New("multipart body already consumed")
+
+// ErrUnsupportedJSONType is returned by [Request.DecodeJSON] when the body isn't JSON.
+var ErrUnsupportedJSONType = errors.New("unsupported JSON content type")
+
+// ErrMalformedJSON is the [JSONError] cause when the body isn't valid JSON.
+var ErrMalformedJSON = errors.New("malformed JSON")
+
+// ErrJSONTypeMismatch is the [JSONError] cause when a JSON value doesn't fit its Go type.
+var ErrJSONTypeMismatch = errors.New("JSON type mismatch")
+
+// ErrUnknownJSONField is the [JSONError] cause when an object has a key matching no struct field.
+var ErrUnknownJSONField = errors.New("unknown JSON field")
+
+// ErrTrailingJSON is the [JSONError] cause when the body has data after the JSON value.
+var ErrTrailingJSON = errors.New("trailing data after JSON value")
+
+// ErrInvalidParam is the [FieldError] cause when a value can't be converted to its field type.
+var ErrInvalidParam = errors.New("invalid parameter")
+
+// ErrInvalidBindTarget is returned by [Request.DecodeParams] when the target isn't a pointer to
+// a struct, or has a field of an unsupported type.
+var ErrInvalidBindTarget = errors.New("invalid bind target")
@@ -0,0 +1,120 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+)
+
+// formMediaType is the only content type parsed by [Request.PostForm].
+const formMediaType = "application/x-www-form-urlencoded"
+
+// form holds the parsed form of a request, see [Request.PostForm].
+type form struct {
+	parsed   bool
+	postForm url.Values
+	err      error
+
+	// multipartStarted is set once the body is handed to a multipart parser
+	multipartStarted bool
+	multipartParsed  bool
+	multipart        *MultipartForm
+	multipartErr     error
+}
+
+// hasBody reports whether the request announces a body.
+func (r *Request) hasBody() bool {
+	return r.ContentLength() > 0 || r.Headers.Get("transfer-encoding") != ""
+}
+
+// limits returns the size limits of the request, which are the defaults for requests that
+// weren't parsed by [RequestFromReader].
+func (r *Request) limits() *SizeLimits {
+	if r.sizeLimits == nil {
+		return &DefaultSizeLimits
+	}
+	return r.sizeLimits
+}
+
+// PostForm parses an application/x-www-form-urlencoded body and returns its values.
+// The result is cached, later calls return the same values and error, and the body can still
+// be read with [Request.Body] afterwards. For multipart/form-data bodies, it returns the
+// values of [Request.MultipartForm] that aren't files.
+//
+// A request without a body has an empty form. Errors are [ErrUnsupportedFormType] if the body
+// has another content type, [ErrBodyTooLarge] if it exceeds [SizeLimits.MaxBodySize], and
+// [ErrMalformedForm] if it can't be decoded.
+func (r *Request) PostForm() (url.Values, error) {
+	if !r.form.parsed {
+		r.form.postForm, r.form.err = r.parsePostForm()
+		r.form.parsed = true
+	}
+	return r.form.postForm, r.form.err
+}
+
+func (r *Request) parsePostForm() (url.Values, error) {
+	contentType := r.Headers.Get("content-type")
+	if contentType == "" && !r.hasBody() {
+		return url.Values{}, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && mediaType == multipartMediaType {
+		mf, err := r.MultipartForm()
+		if err != nil {
+			return url.Values{}, err
+		}
+		return mf.Value, nil
+	}
+	if err != nil || mediaType != formMediaType {
+		return url.Values{}, fmt.Errorf("%w: %q", ErrUnsupportedFormType, contentType)
+	}
+
+	// reject oversized bodies before reading them, and before sending 100 Continue
+	limit := r.limits().MaxBodySize
+	if r.ContentLength() > int64(limit) {
+		return url.Values{}, ErrBodyTooLarge
+	}
+
+	body, err := r.Body()
+	if err != nil {
+		return url.Values{}, err
+	}
+	content, err := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+	if err != nil {
+		return url.Values{}, err
+	}
+	if len(content) > limit {
+		return url.Values{}, ErrBodyTooLarge
+	}
+	r.SetBody(io.NopCloser(bytes.NewReader(content)))
+
+	values, err := url.ParseQuery(string(content))
+	if err != nil {
+		return url.Values{}, fmt.Errorf("%w: %w", ErrMalformedForm, err)
+	}
+	return values, nil
+}
+
+// Form returns the values of the urlencoded body followed by the query parameters, so
+// [url.Values.Get] prefers body values over query values for the same key.
+// If the body can't be parsed, it returns the query parameters alongside the [Request.PostForm] error.
+func (r *Request) Form() (url.Values, error) {
+	postForm, err := r.PostForm()
+	values := make(url.Values, len(postForm)+len(r.Query))
+	for k, v := range postForm {
+		values[k] = append(values[k], v...)
+	}
+	for k, v := range r.Query {
+		values[k] = append(values[k], v...)
+	}
+	return values, err
+}
+
+// FormValue returns the first value for key from [Request.Form], or an empty string.
+// Errors are ignored, use [Request.Form] to check them.
+func (r *Request) FormValue(key string) string {
+	values, _ := r.Form()
+	return values.Get(key)
+}
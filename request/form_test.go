@@ -0,0 +1,129 @@
+package request
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseTestRequest(t *testing.T, raw string, limits *SizeLimits) *Request {
+	t.Helper()
+	r, err := RequestFromReader(&chunkReader{data: raw, numBytesPerRead: 7}, limits)
+	require.NoError(t, err)
+	return r
+}
+
+func TestPostForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected url.Values
+		err      error
+	}{
+		{
+			"urlencoded",
+			"POST / HTTP/1.1\r\nHost: x\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 27\r\n\r\nname=frodo&ring=one&ring=2+",
+			url.Values{"name": {"frodo"}, "ring": {"one", "2 "}},
+			nil,
+		},
+		{
+			"content type parameters",
+			"POST / HTTP/1.1\r\nHost: x\r\nContent-Type: Application/X-WWW-Form-Urlencoded; charset=utf-8\r\nContent-Length: 5\r\n\r\na=%41",
+			url.Values{"a": {"A"}},
+			nil,
+		},
+		{
+			"chunked",
+			"POST / HTTP/1.1\r\nHost: x\r\nContent-Type: application/x-www-form-urlencoded\r\nTransfer-Encoding: chunked\r\n\r\n4\r\na=b&\r\n3\r\nc=d\r\n0\r\n\r\n",
+			url.Values{"a": {"b"}, "c": {"d"}},
+			nil,
+		},
+		{
+			"no body",
+			"GET / HTTP/1.1\r\nHost: x\r\n\r\n",
+			url.Values{},
+			nil,
+		},
+		{
+			"json body",
+			"POST / HTTP/1.1\r\nHost: x\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n{}",
+			url.Values{},
+			ErrUnsupportedFormType,
+		},
+		{
+			"body without content type",
+			"POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 3\r\n\r\na=b",
+			url.Values{},
+			ErrUnsupportedFormType,
+		},
+		{
+			"malformed",
+			"POST / HTTP/1.1\r\nHost: x\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 4\r\n\r\na=%z",
+			url.Values{},
+			ErrMalformedForm,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := parseTestRequest(t, tt.raw, nil)
+			values, err := r.PostForm()
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, values)
+		})
+	}
+}
+
+func TestPostFormTooLarge(t *testing.T) {
+	limits := &SizeLimits{MaxBodySize: 8, MaxChunkSize: 8}
+
+	// rejected from the Content-Length, without reading the body or sending 100 Continue
+	r := parseTestRequest(t, "POST / HTTP/1.1\r\nHost: x\r\nExpect: 100-continue\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 9\r\n\r\na=1234567", limits)
+	r.SetContinueFunc(func() error {
+		t.Error("100 Continue must not be sent for an oversized body")
+		return nil
+	})
+	_, err := r.PostForm()
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	r = parseTestRequest(t, "POST / HTTP/1.1\r\nHost: x\r\nContent-Type: application/x-www-form-urlencoded\r\nTransfer-Encoding: chunked\r\n\r\n5\r\na=123\r\n5\r\n45678\r\n0\r\n\r\n", limits)
+	_, err = r.PostForm()
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+}
+
+func TestFormCaching(t *testing.T) {
+	r := parseTestRequest(t, "POST /?name=sam&page=2 HTTP/1.1\r\nHost: x\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 10\r\n\r\nname=frodo", nil)
+
+	form, err := r.Form()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"frodo", "sam"}, form["name"], "body values come first")
+	assert.Equal(t, "2", form.Get("page"))
+	assert.Equal(t, "frodo", r.FormValue("name"))
+
+	postForm, err := r.PostForm()
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{"name": {"frodo"}}, postForm)
+
+	// the body is still readable
+	body, err := r.Body()
+	require.NoError(t, err)
+	content, err := io.ReadAll(body)
+	require.NoError(t, err)
+	assert.Equal(t, "name=frodo", string(content))
+}
+
+func TestFormUnsupportedTypeKeepsQuery(t *testing.T) {
+	r := parseTestRequest(t, "POST /?q=hobbit HTTP/1.1\r\nHost: x\r\nContent-Type: text/plain\r\nContent-Length: 3\r\n\r\nabc", nil)
+	form, err := r.Form()
+	assert.ErrorIs(t, err, ErrUnsupportedFormType)
+	assert.Equal(t, "hobbit", form.Get("q"))
+	assert.True(t, strings.Contains(err.Error(), "text/plain"))
+}
@@ -0,0 +1,359 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSONOptions configures [Request.DecodeJSON] and [BindJSON]. A nil *JSONOptions selects the defaults.
+type JSONOptions struct {
+	// MaxSize is the maximum size of the body. Defaults to [SizeLimits.MaxBodySize].
+	MaxSize int
+
+	// DisallowUnknownFields rejects objects with keys that don't match a struct field.
+	DisallowUnknownFields bool
+
+	// DisallowTrailingData rejects bodies with anything but whitespace after the JSON value.
+	DisallowTrailingData bool
+}
+
+// JSONError describes why a JSON body couldn't be decoded, and where.
+type JSONError struct {
+	// Err is [ErrMalformedJSON], [ErrJSONTypeMismatch], [ErrUnknownJSONField] or [ErrTrailingJSON].
+	Err error
+
+	// Path locates the offending value or key, e.g. `$.items[2].name`. It is `$` for the whole body.
+	Path string
+
+	// Offset is the byte offset in the body where the error was detected.
+	Offset int64
+
+	// Detail describes the error, e.g. `expected int, got string`.
+	Detail string
+}
+
+func (e *JSONError) Error() string {
+	return fmt.Sprintf("%v at %s (offset %d): %s", e.Err, e.Path, e.Offset, e.Detail)
+}
+
+func (e *JSONError) Unwrap() error {
+	return e.Err
+}
+
+// isJSONMediaType reports whether mediaType is application/json or a structured syntax
+// suffix such as application/problem+json.
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")
+}
+
+// DecodeJSON decodes the JSON body of the request into v, which must be a pointer. The body
+// is buffered, it can be read again with [Request.Body] afterwards.
+//
+// Errors are [ErrUnsupportedJSONType] if the Content-Type isn't JSON, [ErrBodyTooLarge] if the
+// body exceeds the maximum size, and a [*JSONError] if the body isn't valid JSON or doesn't
+// match v. The first two usually map to 415 and 413 responses, the last one to 400.
+func (r *Request) DecodeJSON(v any, opts *JSONOptions) error {
+	if opts == nil {
+		opts = &JSONOptions{}
+	}
+
+	contentType := r.Headers.Get("content-type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !isJSONMediaType(mediaType) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedJSONType, contentType)
+	}
+
+	limit := opts.MaxSize
+	if limit <= 0 {
+		limit = r.limits().MaxBodySize
+	}
+	if r.ContentLength() > int64(limit) {
+		return ErrBodyTooLarge
+	}
+	body, err := r.Body()
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(&maxBytesReader{r: body, n: int64(limit)})
+	if err != nil {
+		return err
+	}
+	r.SetBody(io.NopCloser(bytes.NewReader(data)))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &JSONError{Err: ErrMalformedJSON, Path: "$", Detail: "empty body"}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return jsonError(data, reflect.TypeOf(v), opts.DisallowUnknownFields, err)
+	}
+
+	if opts.DisallowTrailingData {
+		end := dec.InputOffset()
+		rest := data[end:]
+		if trimmed := bytes.TrimLeft(rest, " \t\r\n"); len(trimmed) > 0 {
+			return &JSONError{
+				Err:    ErrTrailingJSON,
+				Path:   "$",
+				Offset: end + int64(len(rest)-len(trimmed)),
+				Detail: "unexpected data after the JSON value",
+			}
+		}
+	}
+	return nil
+}
+
+// BindJSON decodes the JSON body of the request into a new T, see [Request.DecodeJSON].
+func BindJSON[T any](r *Request, opts *JSONOptions) (T, error) {
+	var v T
+	err := r.DecodeJSON(&v, opts)
+	return v, err
+}
+
+// jsonError converts an error of [json.Decoder.Decode] into a [*JSONError]. disallowUnknown
+// reports whether the decoder rejected unknown fields.
+func jsonError(data []byte, target reflect.Type, disallowUnknown bool, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &JSONError{Err: ErrMalformedJSON, Path: jsonPathAt(data, syntaxErr.Offset), Offset: syntaxErr.Offset, Detail: syntaxErr.Error()}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		offset := int64(len(data))
+		return &JSONError{Err: ErrMalformedJSON, Path: jsonPathAt(data, offset), Offset: offset, Detail: "unexpected end of JSON input"}
+	case errors.As(err, &typeErr):
+		return &JSONError{
+			Err:    ErrJSONTypeMismatch,
+			Path:   jsonPathAt(data, typeErr.Offset),
+			Offset: typeErr.Offset,
+			Detail: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value),
+		}
+	}
+
+	var invalidErr *json.InvalidUnmarshalError
+	if errors.As(err, &invalidErr) {
+		return err
+	}
+
+	// the decoder reports unknown fields with an untyped error, without a location: look for
+	// the first key that doesn't match a field rather than relying on the message
+	if disallowUnknown {
+		if name, path, offset, ok := jsonUnknownField(data, target); ok {
+			return &JSONError{Err: ErrUnknownJSONField, Path: path, Offset: offset, Detail: fmt.Sprintf("unknown field %q", name)}
+		}
+	}
+	// errors of custom UnmarshalJSON methods
+	return &JSONError{Err: ErrMalformedJSON, Path: "$", Detail: err.Error()}
+}
+
+// jsonPathElem is an element of a JSON path, an object key or an array index.
+type jsonPathElem struct {
+	key   string
+	index int
+	array bool
+}
+
+var jsonIdentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func formatJSONPath(path []jsonPathElem) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, e := range path {
+		switch {
+		case e.array:
+			fmt.Fprintf(&b, "[%d]", e.index)
+		case jsonIdentRegex.MatchString(e.key):
+			b.WriteString(".")
+			b.WriteString(e.key)
+		default:
+			fmt.Fprintf(&b, "[%s]", strconv.Quote(e.key))
+		}
+	}
+	return b.String()
+}
+
+// jsonFrame is an object or array being walked by walkJSON.
+type jsonFrame struct {
+	jsonPathElem
+	wantKey bool
+}
+
+// walkJSON calls visit for each object key and value of data, along with its path and the
+// offsets where its token starts and ends. Walking stops when visit returns false, or at the
+// first syntax error.
+func walkJSON(data []byte, visit func(path []jsonPathElem, start, end int64, isKey bool, tok json.Token) bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var frames []jsonFrame
+	path := func() []jsonPathElem {
+		elems := make([]jsonPathElem, 0, len(frames))
+		for _, f := range frames {
+			if f.array || f.key != "" {
+				elems = append(elems, f.jsonPathElem)
+			}
+		}
+		return elems
+	}
+
+	for {
+		start := dec.InputOffset()
+		for start < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[start]) >= 0 {
+			start++
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		end := dec.InputOffset()
+
+		var top *jsonFrame
+		if len(frames) > 0 {
+			top = &frames[len(frames)-1]
+		}
+		if top != nil && !top.array && top.wantKey {
+			if key, ok := tok.(string); ok {
+				top.key = key
+				top.wantKey = false
+				if !visit(path(), start, end, true, tok) {
+					return
+				}
+				continue
+			}
+		}
+
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			frames = frames[:len(frames)-1]
+			if len(frames) > 0 && !frames[len(frames)-1].array {
+				frames[len(frames)-1].wantKey = true
+			}
+			continue
+		}
+
+		if top != nil && top.array {
+			top.index++
+		}
+		if !visit(path(), start, end, false, tok) {
+			return
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			frames = append(frames, jsonFrame{jsonPathElem: jsonPathElem{array: delim == '[', index: -1}, wantKey: delim == '{'})
+		} else if top != nil && !top.array {
+			top.wantKey = true
+		}
+	}
+}
+
+// jsonPathAt returns the path of the value at offset in data.
+func jsonPathAt(data []byte, offset int64) string {
+	var last []jsonPathElem
+	walkJSON(data, func(path []jsonPathElem, start, end int64, isKey bool, tok json.Token) bool {
+		if start >= offset && last != nil {
+			return false
+		}
+		last = path
+		return end < offset
+	})
+	return formatJSONPath(last)
+}
+
+// jsonUnknownField returns the name, path and offset of the first key that doesn't match a
+// field of the struct it's decoded into, the way the decoder rejects unknown fields.
+func jsonUnknownField(data []byte, target reflect.Type) (name, path string, offset int64, found bool) {
+	walkJSON(data, func(p []jsonPathElem, start, end int64, isKey bool, tok json.Token) bool {
+		if !isKey {
+			return true
+		}
+		key := tok.(string)
+		parent := resolveJSONType(target, p[:len(p)-1])
+		if parent == nil || parent.Kind() != reflect.Struct {
+			return true
+		}
+		if _, ok := jsonField(parent, key); ok {
+			return true
+		}
+		name, path, offset, found = key, formatJSONPath(p), start, true
+		return false
+	})
+	return name, path, offset, found
+}
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// resolveJSONType returns the type the value at path is decoded into, or nil if unknown or
+// decoded by a [json.Unmarshaler], which handles unknown fields itself.
+func resolveJSONType(t reflect.Type, path []jsonPathElem) reflect.Type {
+	for _, e := range path {
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+			return nil
+		}
+		switch {
+		case e.array && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+			t = t.Elem()
+		case !e.array && t.Kind() == reflect.Map:
+			t = t.Elem()
+		case !e.array && t.Kind() == reflect.Struct:
+			f, ok := jsonField(t, e.key)
+			if !ok {
+				return nil
+			}
+			t = f.Type
+		default:
+			return nil
+		}
+	}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+	return t
+}
+
+// jsonField returns the field of struct type t decoded from the key name, matching names the
+// way encoding/json does: exact match first, then case-insensitive.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	var fold *reflect.StructField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagName, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && tagName == "" && f.Type.Kind() == reflect.Struct {
+			// promoted fields are listed separately
+			continue
+		}
+		fieldName := f.Name
+		if tagName != "" {
+			fieldName = tagName
+		}
+		if fieldName == name {
+			return f, true
+		}
+		if fold == nil && strings.EqualFold(fieldName, name) {
+			fold = &f
+		}
+	}
+	if fold != nil {
+		return *fold, true
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,149 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	Name string `json:"name"`
+	Qty  int    `json:"qty"`
+}
+
+type testOrder struct {
+	ID       int               `json:"id"`
+	Customer string            `json:"customer"`
+	Items    []testItem        `json:"items"`
+	Meta     map[string]string `json:"meta,omitempty"`
+	Ignored  string            `json:"-"`
+}
+
+func newJSONRequest(t *testing.T, contentType, body string, limits *SizeLimits) *Request {
+	t.Helper()
+	raw := fmt.Sprintf("POST /orders HTTP/1.1\r\nHost: x\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s", contentType, len(body), body)
+	return parseTestRequest(t, raw, limits)
+}
+
+func TestBindJSON(t *testing.T) {
+	r := newJSONRequest(t, "application/json; charset=utf-8", `{"id": 7, "customer": "bilbo", "items": [{"name": "ring", "qty": 1}]}`, nil)
+	order, err := BindJSON[testOrder](r, nil)
+	require.NoError(t, err)
+	assert.Equal(t, testOrder{ID: 7, Customer: "bilbo", Items: []testItem{{Name: "ring", Qty: 1}}}, order)
+
+	r = newJSONRequest(t, "application/merge-patch+json", `{"customer": "frodo"}`, nil)
+	order, err = BindJSON[testOrder](r, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "frodo", order.Customer)
+
+	// unknown fields and trailing data are accepted by default
+	r = newJSONRequest(t, "application/json", `{"id": 1, "extra": true} {}`, nil)
+	_, err = BindJSON[testOrder](r, nil)
+	assert.NoError(t, err)
+}
+
+func TestBindJSONErrors(t *testing.T) {
+	strict := &JSONOptions{DisallowUnknownFields: true, DisallowTrailingData: true}
+
+	tests := []struct {
+		name   string
+		body   string
+		opts   *JSONOptions
+		err    error
+		path   string
+		offset int64
+	}{
+		{"empty", "  ", nil, ErrMalformedJSON, "$", 0},
+		{"syntax", `{"id": 1, "customer": tru}`, nil, ErrMalformedJSON, "$.customer", 26},
+		{"truncated", `{"id": 1, "items": [`, nil, ErrMalformedJSON, "$.items", 20},
+		{"type mismatch", `{"id": 1, "items": [{"name": "a", "qty": 1}, {"name": "b", "qty": "two"}]}`, nil, ErrJSONTypeMismatch, "$.items[1].qty", 71},
+		{"type mismatch top level", `[1, 2]`, nil, ErrJSONTypeMismatch, "$", 1},
+		{"unknown field", `{"id": 1, "items": [{"name": "a", "colour": "gold"}]}`, strict, ErrUnknownJSONField, "$.items[0].colour", 34},
+		{"unknown field not confused with map keys", `{"meta": {"colour": "x"}, "colour": 1}`, strict, ErrUnknownJSONField, "$.colour", 26},
+		{"ignored field is unknown", `{"Ignored": "x"}`, strict, ErrUnknownJSONField, "$.Ignored", 1},
+		{"trailing data", `{"id": 1}  {"id": 2}`, strict, ErrTrailingJSON, "$", 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newJSONRequest(t, "application/json", tt.body, nil)
+			_, err := BindJSON[testOrder](r, tt.opts)
+			require.ErrorIs(t, err, tt.err)
+
+			var jsonErr *JSONError
+			require.ErrorAs(t, err, &jsonErr)
+			assert.Equal(t, tt.path, jsonErr.Path)
+			assert.Equal(t, tt.offset, jsonErr.Offset)
+		})
+	}
+}
+
+func TestBindJSONContentTypeAndSize(t *testing.T) {
+	for _, contentType := range []string{"text/plain", "application/jsonx", "application/x-www-form-urlencoded"} {
+		r := newJSONRequest(t, contentType, `{}`, nil)
+		_, err := BindJSON[testOrder](r, nil)
+		assert.ErrorIs(t, err, ErrUnsupportedJSONType, contentType)
+	}
+
+	r := newJSONRequest(t, "application/json", `{"customer": "a very long name"}`, &SizeLimits{MaxBodySize: 16})
+	_, err := BindJSON[testOrder](r, nil)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	r = newJSONRequest(t, "application/json", `{"customer": "a very long name"}`, nil)
+	_, err = BindJSON[testOrder](r, &JSONOptions{MaxSize: 16})
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	raw := "POST / HTTP/1.1\r\nHost: x\r\nContent-Type: application/json\r\nTransfer-Encoding: chunked\r\n\r\n10\r\n{\"customer\": \"ab\r\n10\r\ncdefghijklmnop\"}\r\n0\r\n\r\n"
+	r = parseTestRequest(t, raw, &SizeLimits{MaxBodySize: 64})
+	_, err = BindJSON[testOrder](r, &JSONOptions{MaxSize: 20})
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+}
+
+// testVersion rejects anything but 1, with a message an unknown field would produce.
+type testVersion int
+
+func (v *testVersion) UnmarshalJSON(data []byte) error {
+	if string(data) != "1" {
+		return errors.New(`json: unknown field "version"`)
+	}
+	*v = 1
+	return nil
+}
+
+func TestBindJSONUnknownFieldDetection(t *testing.T) {
+	type versioned struct {
+		Version testVersion `json:"version"`
+	}
+	strict := &JSONOptions{DisallowUnknownFields: true}
+
+	// errors of custom unmarshalers aren't mistaken for unknown fields
+	r := newJSONRequest(t, "application/json", `{"version": 2}`, nil)
+	_, err := BindJSON[versioned](r, strict)
+	require.ErrorIs(t, err, ErrMalformedJSON)
+	assert.NotErrorIs(t, err, ErrUnknownJSONField)
+
+	r = newJSONRequest(t, "application/json", `{"version": 1, "colour": "gold"}`, nil)
+	_, err = BindJSON[versioned](r, strict)
+	var jsonErr *JSONError
+	require.ErrorAs(t, err, &jsonErr)
+	assert.Equal(t, ErrUnknownJSONField, jsonErr.Err)
+	assert.Equal(t, "$.colour", jsonErr.Path)
+	assert.Equal(t, `unknown field "colour"`, jsonErr.Detail)
+}
+
+func TestDecodeJSONKeepsBody(t *testing.T) {
+	body := `{"id": 7}`
+	r := newJSONRequest(t, "application/json", body, nil)
+	_, err := BindJSON[testOrder](r, nil)
+	require.NoError(t, err)
+
+	b, err := r.Body()
+	require.NoError(t, err)
+	data, err := io.ReadAll(b)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(data))
+}
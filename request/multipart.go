@@ -0,0 +1,342 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"os"
+
+	"github.com/shravanasati/shadowfax/headers"
+)
+
+// multipartMediaType is the content type parsed by [Request.MultipartReader].
+const multipartMediaType = "multipart/form-data"
+
+// Part is a part of a multipart/form-data body. Reading it fails with [ErrPartTooLarge] once
+// it exceeds [SizeLimits.MaxMultipartFileSize] for files, or [SizeLimits.MaxMultipartFieldSize]
+// for other fields.
+type Part struct {
+	// FormName is the name of the form field.
+	FormName string
+	// FileName is the base name of the uploaded file, or empty if the part isn't a file.
+	FileName string
+	Headers  *headers.Headers
+
+	part      *multipart.Part
+	remaining int64
+}
+
+// IsFile reports whether the part is a file upload.
+func (p *Part) IsFile() bool {
+	return p.FileName != ""
+}
+
+// Read implements the io.Reader interface.
+func (p *Part) Read(b []byte) (int, error) {
+	if p.remaining < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrPartTooLarge, p.FormName)
+	}
+	if int64(len(b)) > p.remaining+1 {
+		b = b[:p.remaining+1]
+	}
+	n, err := p.part.Read(b)
+	if int64(n) > p.remaining {
+		n = int(p.remaining)
+		p.remaining = -1
+		return n, fmt.Errorf("%w: %q", ErrPartTooLarge, p.FormName)
+	}
+	p.remaining -= int64(n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, multipartError(err)
+	}
+	return n, err
+}
+
+// MultipartReader iterates over the parts of a multipart/form-data body as they arrive,
+// without buffering them. Use [Request.MultipartReader] to create one.
+type MultipartReader struct {
+	mr     *multipart.Reader
+	limits *SizeLimits
+	parts  int
+	err    error
+}
+
+// NextPart returns the next part of the body, or [io.EOF] after the last one. The previous
+// part is discarded. It fails with [ErrTooManyParts] after [SizeLimits.MaxMultipartParts] parts.
+func (m *MultipartReader) NextPart() (*Part, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	p, err := m.mr.NextPart()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			err = multipartError(err)
+		}
+		m.err = err
+		return nil, err
+	}
+
+	m.parts++
+	if m.parts > m.limits.MaxMultipartParts {
+		m.err = ErrTooManyParts
+		return nil, m.err
+	}
+
+	h := headers.NewHeaders()
+	for name, values := range p.Header {
+		h.AddMulti(name, values...)
+	}
+	part := &Part{FormName: p.FormName(), FileName: p.FileName(), Headers: h, part: p}
+	if part.IsFile() {
+		part.remaining = int64(m.limits.MaxMultipartFileSize)
+	} else {
+		part.remaining = int64(m.limits.MaxMultipartFieldSize)
+	}
+	return part, nil
+}
+
+// Parts returns an iterator over the remaining parts. Iteration stops after the first error,
+// which is yielded with a nil part.
+func (m *MultipartReader) Parts() iter.Seq2[*Part, error] {
+	return func(yield func(*Part, error) bool) {
+		for {
+			p, err := m.NextPart()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if !yield(p, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// multipartError wraps parse errors of the multipart body in [ErrMalformedMultipart], keeping
+// the errors of the underlying body reader.
+func multipartError(err error) error {
+	if errors.Is(err, ErrBodyTooLarge) || errors.Is(err, ErrIncompleteRequest) || errors.Is(err, ErrPartTooLarge) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrMalformedMultipart, err)
+}
+
+// maxBytesReader fails with [ErrBodyTooLarge] when more than n bytes are read.
+type maxBytesReader struct {
+	r io.Reader
+	n int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.n < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > m.n+1 {
+		p = p[:m.n+1]
+	}
+	n, err := m.r.Read(p)
+	if int64(n) > m.n {
+		m.n = -1
+		return 0, ErrBodyTooLarge
+	}
+	m.n -= int64(n)
+	return n, err
+}
+
+// MultipartReader returns a [MultipartReader] streaming the parts of a multipart/form-data
+// body, for large uploads that shouldn't be buffered. It can't be combined with
+// [Request.MultipartForm], since both consume the body.
+//
+// Errors are [ErrUnsupportedFormType] for another content type, [ErrBodyTooLarge] if the
+// Content-Length exceeds [SizeLimits.MaxBodySize], and [ErrMultipartConsumed] if the body
+// was already handed to a multipart parser.
+func (r *Request) MultipartReader() (*MultipartReader, error) {
+	if r.form.multipartStarted {
+		return nil, ErrMultipartConsumed
+	}
+	r.form.multipartStarted = true
+	return r.multipartReader()
+}
+
+func (r *Request) multipartReader() (*MultipartReader, error) {
+	contentType := r.Headers.Get("content-type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != multipartMediaType {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormType, contentType)
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, fmt.Errorf("%w: no boundary", ErrMalformedMultipart)
+	}
+
+	limits := r.limits()
+	if r.ContentLength() > int64(limits.MaxBodySize) {
+		return nil, ErrBodyTooLarge
+	}
+	body, err := r.Body()
+	if err != nil {
+		return nil, err
+	}
+	return &MultipartReader{
+		mr:     multipart.NewReader(&maxBytesReader{r: body, n: int64(limits.MaxBodySize)}, boundary),
+		limits: limits,
+	}, nil
+}
+
+// MultipartForm is a parsed multipart/form-data body, see [Request.MultipartForm].
+type MultipartForm struct {
+	// Value holds the fields that aren't files.
+	Value url.Values
+	// File holds the uploaded files, by field name.
+	File map[string][]*FileHeader
+}
+
+// FileHeader is an uploaded file of a [MultipartForm].
+type FileHeader struct {
+	Filename string
+	Headers  *headers.Headers
+	Size     int64
+
+	content []byte
+	tmpFile string
+}
+
+// File is an uploaded file opened with [FileHeader.Open].
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error { return nil }
+
+// Open opens the uploaded file, from memory or from its temp file.
+func (fh *FileHeader) Open() (File, error) {
+	if fh.tmpFile != "" {
+		return os.Open(fh.tmpFile)
+	}
+	return memoryFile{bytes.NewReader(fh.content)}, nil
+}
+
+// InMemory reports whether the file is kept in memory rather than in a temp file.
+func (fh *FileHeader) InMemory() bool {
+	return fh.tmpFile == ""
+}
+
+// RemoveAll removes the temp files of the form.
+func (f *MultipartForm) RemoveAll() error {
+	var errs []error
+	for _, files := range f.File {
+		for _, fh := range files {
+			if fh.tmpFile == "" {
+				continue
+			}
+			if err := os.Remove(fh.tmpFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MultipartForm parses a multipart/form-data body. Fields and small files are kept in memory,
+// files larger than [SizeLimits.MultipartMemory] are spooled to temp files, which are removed
+// by [Request.Cleanup] once the response has been written. The result is cached.
+//
+// Besides the errors of [Request.MultipartReader], it fails with [ErrTooManyParts],
+// [ErrPartTooLarge], [ErrBodyTooLarge] and [ErrMalformedMultipart] as the body is read.
+func (r *Request) MultipartForm() (*MultipartForm, error) {
+	if r.form.multipartParsed {
+		return r.form.multipart, r.form.multipartErr
+	}
+	if r.form.multipartStarted {
+		return nil, ErrMultipartConsumed
+	}
+	r.form.multipartStarted = true
+	r.form.multipartParsed = true
+	r.form.multipart, r.form.multipartErr = r.parseMultipartForm()
+	return r.form.multipart, r.form.multipartErr
+}
+
+func (r *Request) parseMultipartForm() (*MultipartForm, error) {
+	mr, err := r.multipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	form := &MultipartForm{Value: url.Values{}, File: make(map[string][]*FileHeader)}
+	for part, err := range mr.Parts() {
+		if err != nil {
+			form.RemoveAll()
+			return nil, err
+		}
+
+		if !part.IsFile() {
+			value, err := io.ReadAll(part)
+			if err != nil {
+				form.RemoveAll()
+				return nil, err
+			}
+			form.Value.Add(part.FormName, string(value))
+			continue
+		}
+
+		fh, err := spoolPart(part, int64(mr.limits.MultipartMemory))
+		if fh != nil {
+			// added before checking the error, so RemoveAll finds its temp file
+			form.File[part.FormName] = append(form.File[part.FormName], fh)
+		}
+		if err != nil {
+			form.RemoveAll()
+			return nil, err
+		}
+	}
+	return form, nil
+}
+
+// spoolPart reads a file part, in memory up to maxMemory bytes and into a temp file beyond.
+func spoolPart(part *Part, maxMemory int64) (*FileHeader, error) {
+	fh := &FileHeader{Filename: part.FileName, Headers: part.Headers}
+
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, part, maxMemory+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if n <= maxMemory {
+		fh.content = buf.Bytes()
+		fh.Size = n
+		return fh, nil
+	}
+
+	tmp, err := os.CreateTemp("", "shadowfax-multipart-")
+	if err != nil {
+		return nil, err
+	}
+	fh.tmpFile = tmp.Name()
+	size, err := io.Copy(tmp, io.MultiReader(&buf, part))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	fh.Size = size
+	return fh, err
+}
+
+// Cleanup releases the resources held by the request, such as the temp files of
+// [Request.MultipartForm]. The server calls it once the response has been written, requests
+// created otherwise should call it when they're done.
+func (r *Request) Cleanup() error {
+	if r.form.multipart != nil {
+		return r.form.multipart.RemoveAll()
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPart struct {
+	name, filename, content string
+}
+
+// newMultipartRequest parses a request carrying the parts as a multipart/form-data body.
+func newMultipartRequest(t *testing.T, limits *SizeLimits, parts ...testPart) *Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, p := range parts {
+		var pw io.Writer
+		var err error
+		if p.filename != "" {
+			pw, err = w.CreateFormFile(p.name, p.filename)
+		} else {
+			pw, err = w.CreateFormField(p.name)
+		}
+		require.NoError(t, err)
+		io.WriteString(pw, p.content)
+	}
+	require.NoError(t, w.Close())
+
+	raw := fmt.Sprintf("POST /upload HTTP/1.1\r\nHost: x\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s",
+		w.FormDataContentType(), body.Len(), body.String())
+	return parseTestRequest(t, raw, limits)
+}
+
+func TestMultipartReader(t *testing.T) {
+	r := newMultipartRequest(t, nil,
+		testPart{name: "title", content: "There and Back Again"},
+		testPart{name: "book", filename: "../../hobbit.txt", content: "In a hole in the ground"},
+	)
+	mr, err := r.MultipartReader()
+	require.NoError(t, err)
+
+	var got []string
+	for part, err := range mr.Parts() {
+		require.NoError(t, err)
+		content, err := io.ReadAll(part)
+		require.NoError(t, err)
+		got = append(got, fmt.Sprintf("%s|%s|%v|%s", part.FormName, part.FileName, part.IsFile(), content))
+	}
+	assert.Equal(t, []string{
+		"title||false|There and Back Again",
+		"book|hobbit.txt|true|In a hole in the ground",
+	}, got)
+
+	_, err = r.MultipartReader()
+	assert.ErrorIs(t, err, ErrMultipartConsumed)
+	_, err = r.MultipartForm()
+	assert.ErrorIs(t, err, ErrMultipartConsumed)
+}
+
+func TestMultipartForm(t *testing.T) {
+	big := strings.Repeat("x", 64)
+	r := newMultipartRequest(t, &SizeLimits{MultipartMemory: 16},
+		testPart{name: "title", content: "hobbit"},
+		testPart{name: "tags", content: "fantasy"},
+		testPart{name: "tags", content: "adventure"},
+		testPart{name: "small", filename: "small.txt", content: "tiny"},
+		testPart{name: "big", filename: "big.txt", content: big},
+	)
+
+	form, err := r.MultipartForm()
+	require.NoError(t, err)
+	assert.Equal(t, "hobbit", form.Value.Get("title"))
+	assert.Equal(t, []string{"fantasy", "adventure"}, form.Value["tags"])
+
+	small := form.File["small"][0]
+	assert.True(t, small.InMemory())
+	assert.Equal(t, int64(4), small.Size)
+	assert.Equal(t, "small.txt", small.Filename)
+
+	bigFile := form.File["big"][0]
+	assert.False(t, bigFile.InMemory())
+	assert.Equal(t, int64(64), bigFile.Size)
+	f, err := bigFile.Open()
+	require.NoError(t, err)
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	f.Close()
+	assert.Equal(t, big, string(content))
+
+	// cached, and shared with PostForm
+	again, err := r.MultipartForm()
+	require.NoError(t, err)
+	assert.Same(t, form, again)
+	postForm, err := r.PostForm()
+	require.NoError(t, err)
+	assert.Equal(t, "hobbit", postForm.Get("title"))
+
+	require.NoError(t, r.Cleanup())
+	_, err = os.Stat(bigFile.tmpFile)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestMultipartLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits *SizeLimits
+		parts  []testPart
+		err    error
+	}{
+		{
+			"too many parts",
+			&SizeLimits{MaxMultipartParts: 2},
+			[]testPart{{name: "a", content: "1"}, {name: "b", content: "2"}, {name: "c", content: "3"}},
+			ErrTooManyParts,
+		},
+		{
+			"field too large",
+			&SizeLimits{MaxMultipartFieldSize: 4},
+			[]testPart{{name: "a", content: "12345"}},
+			ErrPartTooLarge,
+		},
+		{
+			"file too large",
+			&SizeLimits{MaxMultipartFileSize: 8, MultipartMemory: 2},
+			[]testPart{{name: "f", filename: "f.bin", content: "123456789"}},
+			ErrPartTooLarge,
+		},
+		{
+			"body too large",
+			&SizeLimits{MaxBodySize: 32},
+			[]testPart{{name: "f", filename: "f.bin", content: strings.Repeat("x", 100)}},
+			ErrBodyTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newMultipartRequest(t, tt.limits, tt.parts...)
+			_, err := r.MultipartForm()
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestMultipartFormRemovesTempFilesOnError(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+
+	r := newMultipartRequest(t, &SizeLimits{MultipartMemory: 2, MaxMultipartParts: 1},
+		testPart{name: "f", filename: "f.bin", content: "spooled to disk"},
+		testPart{name: "g", content: "one part too many"},
+	)
+	_, err := r.MultipartForm()
+	assert.ErrorIs(t, err, ErrTooManyParts)
+
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestMultipartMalformed(t *testing.T) {
+	r := parseTestRequest(t, "POST / HTTP/1.1\r\nHost: x\r\nContent-Type: multipart/form-data\r\nContent-Length: 0\r\n\r\n", nil)
+	_, err := r.MultipartForm()
+	assert.ErrorIs(t, err, ErrMalformedMultipart)
+
+	body := "--b\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\nvalue without closing boundary"
+	raw := fmt.Sprintf("POST / HTTP/1.1\r\nHost: x\r\nContent-Type: multipart/form-data; boundary=b\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	r = parseTestRequest(t, raw, nil)
+	_, err = r.MultipartForm()
+	assert.ErrorIs(t, err, ErrMalformedMultipart)
+
+	r = parseTestRequest(t, "POST / HTTP/1.1\r\nHost: x\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n{}", nil)
+	_, err = r.MultipartReader()
+	assert.ErrorIs(t, err, ErrUnsupportedFormType)
+}
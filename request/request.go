@@ -2,6 +2,8 @@ package request
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -43,22 +45,47 @@ type Request struct {
 	Headers    headers.Headers
 	PathParams map[string]string
 	Query      url.Values
+
+	// TLS holds the state of the TLS connection the request was received on,
+	// including the negotiated version, cipher suite, SNI server name and peer certificates.
+	// It is nil for plain HTTP connections.
+	TLS *tls.ConnectionState
+
 	reader     io.Reader
 	sizeLimits *SizeLimits
+	ctx        context.Context
+
+	// continueFunc sends the interim 100 Continue response, see [Request.SetContinueFunc]
+	continueFunc func() error
+
+	form form
 }
 
-var requestLineRegex = regexp.MustCompile(`^(GET|POST|PUT|PATCH|OPTIONS|TRACE|DELETE|HEAD) ([^\s]*) HTTP\/1.1$`)
+// methods are tokens, see https://datatracker.ietf.org/doc/html/rfc9110#name-methods
+const methodPattern = `[a-zA-Z0-9!#$%&'*\+\-.^_\x60\|~]+`
+
+var (
+	requestLineRegex = regexp.MustCompile(`^(` + methodPattern + `) ([^\s]*) HTTP\/(1\.[01])$`)
+	methodRegex      = regexp.MustCompile(`^` + methodPattern + `$`)
+)
+
+// IsValidMethod reports whether method is a syntactically valid HTTP method.
+// Any token is a valid method, including extension methods such as PROPFIND or QUERY.
+// Methods are case-sensitive.
+func IsValidMethod(method string) bool {
+	return methodRegex.MatchString(method)
+}
 
 func parseRequestLine(reqLine []byte) (*RequestLine, error) {
 	matches := requestLineRegex.FindSubmatch(reqLine)
-	if matches == nil || len(matches) != 3 {
+	if matches == nil || len(matches) != 4 {
 		return nil, ErrIncorrectRequestLine
 	}
 
 	return &RequestLine{
 		Method:      string(matches[1]),
 		Target:      string(matches[2]),
-		HTTPVersion: "1.1",
+		HTTPVersion: string(matches[3]),
 	}, nil
 }
 
@@ -162,7 +189,14 @@ var nonMergeableHeaders = []string{
 
 func validateFraming(req *Request) error {
 	hostVal := req.Headers.Get("host")
-	if hostVal == "" {
+	if hostVal == "" && !req.IsHTTP10() {
+		// the host header was introduced in HTTP/1.1
+		return ErrInvalidFraming
+	}
+
+	if req.IsHTTP10() && req.Headers.Get("transfer-encoding") != "" {
+		// transfer codings don't exist in HTTP/1.0, the framing can't be trusted
+		// https://datatracker.ietf.org/doc/html/rfc9112#section-6.1-16
 		return ErrInvalidFraming
 	}
 
@@ -192,6 +226,32 @@ func validateFraming(req *Request) error {
 	return nil
 }
 
+// IsHTTP10 reports whether the request was sent with HTTP/1.0.
+func (r *Request) IsHTTP10() bool {
+	return r.HTTPVersion == "1.0"
+}
+
+// WantsKeepAlive reports whether the client wants to reuse the connection after this request.
+// HTTP/1.1 connections are persistent unless the client sends `Connection: close`,
+// HTTP/1.0 connections are closed unless the client sends `Connection: keep-alive`.
+func (r *Request) WantsKeepAlive() bool {
+	connection := r.Headers.Get("connection")
+	if r.IsHTTP10() {
+		return hasToken(connection, "keep-alive")
+	}
+	return !hasToken(connection, "close")
+}
+
+// hasToken reports whether the comma separated header value contains token, case-insensitively.
+func hasToken(value, token string) bool {
+	for part := range strings.SplitSeq(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *Request) ContentLength() int64 {
 	contentLength := r.Headers.Get("content-length")
 	if contentLength == "" {
@@ -271,7 +331,7 @@ func (cbr *chunkedBodyReader) Read(p []byte) (n int, err error) {
 		for i := range allowedTrailers {
 			allowedTrailers[i] = headers.NormalizeKey(allowedTrailers[i])
 		}
-		for k, v := range cbr.cr.Trailers().All() {
+		for k, v := range cbr.cr.Trailers().Fields() {
 			if slices.Contains(allowedTrailers, k) && !slices.Contains(denyTrailers, k) {
 				cbr.req.Headers.Add(k, v)
 			}
@@ -284,9 +344,41 @@ func (cbr *chunkedBodyReader) Close() error {
 	return cbr.cr.Close()
 }
 
+// ExpectsContinue reports whether the client sent `Expect: 100-continue` and waits for an
+// interim 100 Continue response before sending the body. The expectation is ignored for
+// HTTP/1.0 requests, as required by RFC 9110.
+//
+// Handlers and middleware can reject such a request with a final response (e.g. 417
+// Expectation Failed or 413 Content Too Large) without calling [Request.Body], in which
+// case the client never sends the body.
+func (r *Request) ExpectsContinue() bool {
+	return !r.IsHTTP10() && strings.EqualFold(strings.TrimSpace(r.Headers.Get("expect")), "100-continue")
+}
+
+// SetContinueFunc sets the function sending the interim 100 Continue response. It is called
+// once, by the first call to [Request.Body]. Used by the server for requests that
+// [Request.ExpectsContinue].
+func (r *Request) SetContinueFunc(fn func() error) {
+	r.continueFunc = fn
+}
+
+// SetBody replaces the request body, so later calls to [Request.Body] return body.
+// Middleware that read the body before the handler use it to hand the body on.
+func (r *Request) SetBody(body io.ReadCloser) {
+	r.reader = body
+}
+
 // Body returns an [io.ReadCloser] for the request body.
 // Make sure to close the body after it has been used.
+// If the client expects a 100 Continue response, it is sent by the first call.
 func (r *Request) Body() (io.ReadCloser, error) {
+	if fn := r.continueFunc; fn != nil {
+		r.continueFunc = nil
+		if err := fn(); err != nil {
+			return nil, err
+		}
+	}
+
 	if br, ok := r.reader.(io.ReadCloser); ok {
 		return br, nil
 	}
@@ -314,5 +406,8 @@ func (r *Request) Body() (io.ReadCloser, error) {
 
 	// check for content-length header next
 	contentLength := r.ContentLength()
-	return newBodyReader(r.reader, int64(contentLength)), nil
+	br := newBodyReader(r.reader, int64(contentLength))
+	// later calls return the same reader, so the body isn't consumed twice
+	r.reader = br
+	return br, nil
 }
@@ -88,6 +88,71 @@ func TestRequestLineParse(t *testing.T) {
 	}
 	_, err = RequestFromReader(reader, nil)
 	require.Error(t, err)
+
+	// Test: Extension method
+	reader = &chunkReader{
+		data:            "PROPFIND /files HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 4,
+	}
+	r, err = RequestFromReader(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "PROPFIND", r.RequestLine.Method)
+
+	// Test: Invalid method token
+	reader = &chunkReader{
+		data:            "GE(T / HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 4,
+	}
+	_, err = RequestFromReader(reader, nil)
+	require.Error(t, err)
+
+	// Test: Unsupported version in Request line
+	reader = &chunkReader{
+		data:            "GET / HTTP/2.0\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 6,
+	}
+	_, err = RequestFromReader(reader, nil)
+	require.Error(t, err)
+}
+
+func TestHTTP10Request(t *testing.T) {
+	// Test: HTTP/1.0 request without a host
+	reader := &chunkReader{
+		data:            "GET /health HTTP/1.0\r\nUser-Agent: ApacheBench/2.3\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestFromReader(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0", r.HTTPVersion)
+	assert.True(t, r.IsHTTP10())
+	assert.False(t, r.WantsKeepAlive())
+
+	// Test: HTTP/1.0 keep-alive
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.0\r\nConnection: Keep-Alive\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader, nil)
+	require.NoError(t, err)
+	assert.True(t, r.WantsKeepAlive())
+
+	// Test: HTTP/1.0 with transfer encoding
+	reader = &chunkReader{
+		data:            "POST / HTTP/1.0\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReader(reader, nil)
+	require.ErrorIs(t, err, ErrInvalidFraming)
+
+	// Test: HTTP/1.1 connections persist unless closed
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: x\r\nConnection: upgrade, close\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader, nil)
+	require.NoError(t, err)
+	assert.False(t, r.IsHTTP10())
+	assert.False(t, r.WantsKeepAlive())
 }
 
 func TestHeadersParse(t *testing.T) {
@@ -775,3 +840,63 @@ func TestSizeLimits(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestExpectContinue(t *testing.T) {
+	reader := &chunkReader{
+		data:            "POST / HTTP/1.1\r\nHost: x\r\nExpect: 100-Continue\r\nContent-Length: 5\r\n\r\nhello",
+		numBytesPerRead: 4,
+	}
+	r, err := RequestFromReader(reader, nil)
+	require.NoError(t, err)
+	require.True(t, r.ExpectsContinue())
+
+	calls := 0
+	r.SetContinueFunc(func() error {
+		calls++
+		return nil
+	})
+
+	body, err := r.Body()
+	require.NoError(t, err)
+	buf := make([]byte, 2)
+	_, err = io.ReadFull(body, buf)
+	require.NoError(t, err)
+
+	// later calls continue where the first reader stopped
+	body, err = r.Body()
+	require.NoError(t, err)
+	rest, err := io.ReadAll(body)
+	require.NoError(t, err)
+	assert.Equal(t, "llo", string(rest))
+	assert.Equal(t, 1, calls)
+
+	// HTTP/1.0 expectations are ignored
+	reader = &chunkReader{
+		data:            "POST / HTTP/1.0\r\nExpect: 100-continue\r\n\r\n",
+		numBytesPerRead: 4,
+	}
+	r, err = RequestFromReader(reader, nil)
+	require.NoError(t, err)
+	assert.False(t, r.ExpectsContinue())
+}
+
+func TestSetBody(t *testing.T) {
+	reader := &chunkReader{
+		data:            "POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\n\r\nhello",
+		numBytesPerRead: 4,
+	}
+	r, err := RequestFromReader(reader, nil)
+	require.NoError(t, err)
+
+	body, err := r.Body()
+	require.NoError(t, err)
+	content, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	r.SetBody(io.NopCloser(strings.NewReader(string(content))))
+	body, err = r.Body()
+	require.NoError(t, err)
+	again, err := io.ReadAll(body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(again))
+}
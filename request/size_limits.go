@@ -16,6 +16,18 @@ type SizeLimits struct {
 
 	// Max size of the accumulated body. Defaults to 10MiB.
 	MaxBodySize int
+
+	// Max number of parts in a multipart body. Defaults to 1000.
+	MaxMultipartParts int
+
+	// Max size of a multipart field that isn't a file. Defaults to 1MiB.
+	MaxMultipartFieldSize int
+
+	// Max size of a file in a multipart body. Defaults to 10MiB.
+	MaxMultipartFileSize int
+
+	// Size above which files are spooled to disk by [Request.MultipartForm]. Defaults to 1MiB.
+	MultipartMemory int
 }
 
 const kib = 1024
@@ -26,6 +38,10 @@ const maxHeaderLineBytes = 8 * kib
 const maxHeadersBytes = 64 * kib
 const maxChunkSizeBytes = mib
 const maxBodyBytes = 10 * mib
+const maxMultipartParts = 1000
+const maxMultipartFieldBytes = mib
+const maxMultipartFileBytes = 10 * mib
+const multipartMemoryBytes = mib
 
 var DefaultSizeLimits = SizeLimits{
 	MaxRequestLine: maxRequestLineBytes,
@@ -34,6 +50,11 @@ var DefaultSizeLimits = SizeLimits{
 
 	MaxChunkSize: maxChunkSizeBytes,
 	MaxBodySize:  maxBodyBytes,
+
+	MaxMultipartParts:     maxMultipartParts,
+	MaxMultipartFieldSize: maxMultipartFieldBytes,
+	MaxMultipartFileSize:  maxMultipartFileBytes,
+	MultipartMemory:       multipartMemoryBytes,
 }
 
 // Fills empty size limits with defaults.
@@ -56,6 +77,18 @@ func fillEmptySizeLimits(sl *SizeLimits) *SizeLimits {
 	if sl.MaxBodySize == 0 {
 		sl.MaxBodySize = maxBodyBytes
 	}
+	if sl.MaxMultipartParts == 0 {
+		sl.MaxMultipartParts = maxMultipartParts
+	}
+	if sl.MaxMultipartFieldSize == 0 {
+		sl.MaxMultipartFieldSize = maxMultipartFieldBytes
+	}
+	if sl.MaxMultipartFileSize == 0 {
+		sl.MaxMultipartFileSize = maxMultipartFileBytes
+	}
+	if sl.MultipartMemory == 0 {
+		sl.MultipartMemory = multipartMemoryBytes
+	}
 
 	return sl
 }
@@ -3,6 +3,7 @@ package response
 import (
 	"io"
 
+	"github.com/shravanasati/shadowfax/cookie"
 	"github.com/shravanasati/shadowfax/headers"
 )
 
@@ -63,6 +64,23 @@ func (r *BaseResponse) WithBody(body io.Reader) Response {
 	return r
 }
 
+// WithCookie adds a Set-Cookie header for the cookie, unless it is invalid.
+func (r *BaseResponse) WithCookie(c *cookie.Cookie) Response {
+	SetCookie(r, c)
+	return r
+}
+
+// SetCookie adds a Set-Cookie header for the cookie to resp. Each cookie gets its own header
+// line. Invalid cookies (see [cookie.Cookie.Valid]) aren't added, to prevent header injection,
+// and the validation error is returned.
+func SetCookie(resp Response, c *cookie.Cookie) error {
+	if err := c.Valid(); err != nil {
+		return err
+	}
+	resp.GetHeaders().Add("Set-Cookie", c.String())
+	return nil
+}
+
 // Write writes the response to the given writer.
 func (r *BaseResponse) Write(w io.Writer) error {
 	rw := NewResponseWriter(w)
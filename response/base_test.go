@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/shravanasati/shadowfax/cookie"
 	"github.com/shravanasati/shadowfax/headers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -188,6 +189,43 @@ func TestResponseWriterHeaders(t *testing.T) {
 	assert.Equal(t, errors.Unwrap(err), ErrInvalidWriterState)
 }
 
+func TestResponseWriterRepeatedHeaders(t *testing.T) {
+	var buf strings.Builder
+	rw := NewResponseWriter(&buf)
+	require.NoError(t, rw.WriteStatusLine(200))
+
+	h := headers.NewHeaders()
+	h.Add("Set-Cookie", "a=1")
+	h.Add("Content-Type", "text/plain")
+	h.Add("Set-Cookie", "b=2")
+	require.NoError(t, rw.WriteHeaders(h))
+
+	assert.Equal(t, "HTTP/1.1 200 OK\r\nset-cookie: a=1\r\nset-cookie: b=2\r\ncontent-type: text/plain\r\n\r\n", buf.String())
+}
+
+func TestSetCookie(t *testing.T) {
+	resp := NewBaseResponse()
+	require.NoError(t, SetCookie(resp, &cookie.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true}))
+	require.NoError(t, SetCookie(resp, &cookie.Cookie{Name: "theme", Value: "dark"}))
+	assert.Error(t, SetCookie(resp, &cookie.Cookie{Name: "evil", Value: "x\r\nX-Injected: 1"}))
+
+	assert.Equal(t, []string{"session=abc; Path=/; HttpOnly", "theme=dark"}, resp.GetHeaders().Values("set-cookie"))
+
+	var buf strings.Builder
+	require.NoError(t, resp.Write(&buf))
+	assert.Contains(t, buf.String(), "set-cookie: session=abc; Path=/; HttpOnly\r\nset-cookie: theme=dark\r\n")
+	assert.NotContains(t, buf.String(), "X-Injected")
+}
+
+func TestBaseResponseWithCookie(t *testing.T) {
+	resp := NewBaseResponse().
+		WithCookie(&cookie.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true}).
+		WithCookie(&cookie.Cookie{Name: "evil", Value: "x\r\nX-Injected: 1"}).
+		WithCookie(&cookie.Cookie{Name: "theme", Value: "dark"})
+
+	assert.Equal(t, []string{"session=abc; Path=/; HttpOnly", "theme=dark"}, resp.GetHeaders().Values("set-cookie"))
+}
+
 func TestResponseWriterBody(t *testing.T) {
 	var buf strings.Builder
 	rw := NewResponseWriter(&buf)
@@ -227,6 +265,34 @@ func TestResponseWriterBodyClosesCloser(t *testing.T) {
 	assert.Contains(t, buf.String(), "close me")
 }
 
+func TestResponseWriterInformational(t *testing.T) {
+	var buf strings.Builder
+	rw := NewResponseWriter(&buf)
+
+	h := headers.NewHeaders()
+	h.Add("Link", "</style.css>; rel=preload; as=style")
+	require.NoError(t, rw.WriteInformational(StatusEarlyHints, h))
+	require.NoError(t, rw.WriteInformational(StatusProcessing, nil))
+	require.NoError(t, rw.WriteStatusLine(200))
+
+	assert.Equal(t, "HTTP/1.1 103 Early Hints\r\nlink: </style.css>; rel=preload; as=style\r\n\r\n"+
+		"HTTP/1.1 102 Processing\r\n\r\n"+
+		"HTTP/1.1 200 OK\r\n", buf.String())
+
+	// Test informational responses after the final status line (should fail)
+	err := rw.WriteInformational(StatusEarlyHints, nil)
+	assert.ErrorIs(t, err, ErrInvalidWriterState)
+
+	// Test non informational and 101 status codes (should fail)
+	rw = NewResponseWriter(&buf)
+	assert.ErrorIs(t, rw.WriteInformational(StatusOK, nil), ErrInvalidInformationalStatus)
+	assert.ErrorIs(t, rw.WriteInformational(StatusSwitchingProtocols, nil), ErrInvalidInformationalStatus)
+
+	// Test HTTP/1.0 clients (should fail)
+	rw = NewResponseWriter(&http10Writer{&buf})
+	assert.ErrorIs(t, rw.WriteInformational(StatusEarlyHints, nil), ErrInformationalUnsupported)
+}
+
 func TestResponseWriterStateMachine(t *testing.T) {
 	var buf strings.Builder
 	rw := NewResponseWriter(&buf)
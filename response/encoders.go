@@ -0,0 +1,163 @@
+package response
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// EncodeJSON writes v as JSON.
+func EncodeJSON(w io.Writer, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// EncodeXML writes v as an XML document. Slices and arrays are wrapped in an `<items>` root
+// element, since a document has a single root. Values encoding/xml can't marshal, such as
+// maps, are [ErrUnencodable].
+func EncodeXML(w io.Writer, v any) error {
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if err := encodeXMLRoot(enc, v); err != nil {
+		var unsupported *xml.UnsupportedTypeError
+		if errors.As(err, &unsupported) {
+			return fmt.Errorf("%w: %w", ErrUnencodable, err)
+		}
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// encodeXMLRoot encodes v, wrapping slices and arrays (but not []byte) in an `<items>` element.
+func encodeXMLRoot(enc *xml.Encoder, v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return enc.Encode(v)
+	}
+
+	root := xml.StartElement{Name: xml.Name{Local: "items"}}
+	if err := enc.EncodeToken(root); err != nil {
+		return err
+	}
+	for i := range rv.Len() {
+		if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(root.End())
+}
+
+// EncodeCSV writes v as CSV. v must be a [][]string, or a slice of structs, which is written
+// with a header row. Column names are taken from the csv tag of the fields, or else their name,
+// and fields tagged `csv:"-"` are skipped.
+func EncodeCSV(w io.Writer, v any) error {
+	cw := csv.NewWriter(w)
+	if records, ok := v.([][]string); ok {
+		return cw.WriteAll(records)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Errorf("%w: %T as CSV", ErrUnencodable, v)
+	}
+	t := rv.Type().Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T as CSV", ErrUnencodable, v)
+	}
+
+	var fields []int
+	var header []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("csv"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, i)
+		header = append(header, name)
+	}
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(fields))
+	for i := range rv.Len() {
+		elem := rv.Index(i)
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		for j, f := range fields {
+			record[j] = ""
+			if elem.IsValid() {
+				record[j] = formatText(elem.Field(f).Interface())
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// EncodeText writes strings, byte slices, numbers, bools, errors and values implementing
+// [fmt.Stringer] or [encoding.TextMarshaler] as plain text.
+func EncodeText(w io.Writer, v any) error {
+	switch v := v.(type) {
+	case string:
+		_, err := io.WriteString(w, v)
+		return err
+	case []byte:
+		_, err := w.Write(v)
+		return err
+	case fmt.Stringer, error, encoding.TextMarshaler:
+		_, err := io.WriteString(w, formatText(v))
+		return err
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String:
+		_, err := fmt.Fprint(w, v)
+		return err
+	}
+	return fmt.Errorf("%w: %T as text", ErrUnencodable, v)
+}
+
+// formatText formats a value for text and CSV.
+func formatText(v any) string {
+	if m, ok := v.(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(v)
+}
@@ -4,3 +4,15 @@ import "errors"
 
 // ErrInvalidWriterState is returned when the response writer state is not what is called.
 var ErrInvalidWriterState = errors.New("invalid writer state")
+
+// ErrInvalidInformationalStatus is returned when writing an informational response with a status code outside of 1xx, or with 101 Switching Protocols.
+var ErrInvalidInformationalStatus = errors.New("invalid informational status code")
+
+// ErrInformationalUnsupported is returned when writing an informational response to an HTTP/1.0 client, which doesn't understand them.
+var ErrInformationalUnsupported = errors.New("informational responses are not supported by HTTP/1.0 clients")
+
+// ErrUnencodable is returned by an [Encoder] for values it can't represent in its media type.
+var ErrUnencodable = errors.New("value cannot be encoded in this media type")
+
+// ErrInvalidMediaType is returned when registering an encoder for an invalid or wildcard media type.
+var ErrInvalidMediaType = errors.New("invalid media type")
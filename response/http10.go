@@ -0,0 +1,172 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// http10Writer marks the connection of an HTTP/1.0 client, status lines written to it
+// carry the HTTP/1.0 version.
+type http10Writer struct {
+	io.Writer
+}
+
+// http10Response writes the wrapped response for an HTTP/1.0 client.
+type http10Response struct {
+	Response
+}
+
+func (r *http10Response) Write(w io.Writer) error {
+	return r.Response.Write(&http10Writer{w})
+}
+
+// bodyCloser reads from a reader and closes the original body when done.
+type bodyCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func closeBody(body io.Reader) {
+	if closer, ok := body.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// ForHTTP10 adapts a response for an HTTP/1.0 client. Its status line carries the
+// HTTP/1.0 version, and since HTTP/1.0 has no chunked transfer coding, chunked bodies
+// are sent as-is, without trailers.
+//
+// Chunked bodies of up to maxBuffer bytes are buffered and sent with a Content-Length.
+// Larger bodies and stream responses can only be delimited by closing the connection,
+// which is reported by the returned bool.
+func ForHTTP10(resp Response, maxBuffer int) (Response, bool, error) {
+	h := resp.GetHeaders()
+	if !strings.Contains(strings.ToLower(h.Get("transfer-encoding")), "chunked") || resp.GetBody() == nil {
+		return &http10Response{resp}, false, nil
+	}
+
+	h.Remove("transfer-encoding")
+	h.Remove("trailer")
+
+	var body io.Reader
+	if cr, ok := resp.GetBody().(*chunkedReader); ok {
+		body = cr.r
+	} else {
+		body = newChunkDecoder(resp.GetBody())
+	}
+
+	if _, ok := resp.(*StreamResponse); ok {
+		// send stream data as it is produced
+		resp.WithBody(body)
+		return &http10Response{resp}, true, nil
+	}
+
+	var buf bytes.Buffer
+	_, err := io.CopyN(&buf, body, int64(maxBuffer)+1)
+	if err == io.EOF {
+		closeBody(body)
+		h.Set("content-length", strconv.Itoa(buf.Len()))
+		resp.WithBody(&buf)
+		return &http10Response{resp}, false, nil
+	}
+	if err != nil {
+		closeBody(body)
+		return nil, false, err
+	}
+
+	// too large to buffer, send the rest after the buffered part
+	var closer io.Closer = io.NopCloser(nil)
+	if c, ok := body.(io.Closer); ok {
+		closer = c
+	}
+	resp.WithBody(&bodyCloser{Reader: io.MultiReader(&buf, body), Closer: closer})
+	return &http10Response{resp}, true, nil
+}
+
+var errMalformedChunk = errors.New("malformed chunked body")
+
+// chunkDecoder decodes a body with chunked transfer coding, discarding extensions and trailers.
+type chunkDecoder struct {
+	src       io.Reader
+	r         *bufio.Reader
+	remaining int64
+	needCRLF  bool
+	done      bool
+}
+
+func newChunkDecoder(src io.Reader) *chunkDecoder {
+	return &chunkDecoder{src: src, r: bufio.NewReader(src)}
+}
+
+func (d *chunkDecoder) readLine() (string, error) {
+	line, err := d.r.ReadString('\n')
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (d *chunkDecoder) Read(p []byte) (int, error) {
+	if d.done {
+		return 0, io.EOF
+	}
+
+	if d.remaining == 0 {
+		if d.needCRLF {
+			if line, err := d.readLine(); err != nil || line != "" {
+				return 0, errors.Join(errMalformedChunk, err)
+			}
+			d.needCRLF = false
+		}
+
+		line, err := d.readLine()
+		if err != nil {
+			return 0, err
+		}
+		sizeStr, _, _ := strings.Cut(line, ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeStr), 16, 64)
+		if err != nil || size < 0 {
+			return 0, fmt.Errorf("%w: invalid chunk size %q", errMalformedChunk, sizeStr)
+		}
+
+		if size == 0 {
+			// skip the trailers
+			for {
+				line, err := d.readLine()
+				if err != nil {
+					return 0, err
+				}
+				if line == "" {
+					break
+				}
+			}
+			d.done = true
+			return 0, io.EOF
+		}
+		d.remaining = size
+		d.needCRLF = true
+	}
+
+	if int64(len(p)) > d.remaining {
+		p = p[:d.remaining]
+	}
+	n, err := d.r.Read(p)
+	d.remaining -= int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Close closes the source reader if it is an [io.Closer].
+func (d *chunkDecoder) Close() error {
+	if closer, ok := d.src.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
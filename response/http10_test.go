@@ -0,0 +1,78 @@
+package response
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeHTTP10(t *testing.T, resp Response, maxBuffer int) (string, bool) {
+	t.Helper()
+	resp, closeDelimited, err := ForHTTP10(resp, maxBuffer)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, resp.Write(&buf))
+	return buf.String(), closeDelimited
+}
+
+func TestForHTTP10(t *testing.T) {
+	t.Run("content length response", func(t *testing.T) {
+		out, closeDelimited := writeHTTP10(t, NewTextResponse("hello"), 1024)
+		assert.False(t, closeDelimited)
+		assert.True(t, strings.HasPrefix(out, "HTTP/1.0 200 OK\r\n"))
+		assert.True(t, strings.HasSuffix(out, "\r\n\r\nhello"))
+	})
+
+	t.Run("stream is delimited by close", func(t *testing.T) {
+		resp := NewStreamResponse(func(w io.Writer, setTrailer TrailerSetter) error {
+			w.Write([]byte("Hello "))
+			w.Write([]byte("World"))
+			setTrailer("X-Checksum", "abc")
+			return nil
+		}, []string{"X-Checksum"})
+
+		out, closeDelimited := writeHTTP10(t, resp, 1024)
+		assert.True(t, closeDelimited)
+		assert.True(t, strings.HasPrefix(out, "HTTP/1.0 200 OK\r\n"))
+		assert.NotContains(t, strings.ToLower(out), "transfer-encoding")
+		assert.NotContains(t, strings.ToLower(out), "trailer")
+		assert.True(t, strings.HasSuffix(out, "\r\n\r\nHello World"))
+	})
+
+	t.Run("small chunked body is buffered", func(t *testing.T) {
+		resp := NewBaseResponse().
+			WithHeader("transfer-encoding", "chunked").
+			WithBody(strings.NewReader("5;ext=1\r\nhello\r\n6\r\n world\r\n0\r\nX-Trailer: v\r\n\r\n"))
+
+		out, closeDelimited := writeHTTP10(t, resp, 1024)
+		assert.False(t, closeDelimited)
+		assert.Contains(t, out, "content-length: 11\r\n")
+		assert.NotContains(t, out, "transfer-encoding")
+		assert.True(t, strings.HasSuffix(out, "\r\n\r\nhello world"))
+	})
+
+	t.Run("large chunked body is delimited by close", func(t *testing.T) {
+		resp := NewBaseResponse().
+			WithHeader("transfer-encoding", "chunked").
+			WithBody(strings.NewReader("5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n"))
+
+		out, closeDelimited := writeHTTP10(t, resp, 4)
+		assert.True(t, closeDelimited)
+		assert.NotContains(t, out, "content-length")
+		assert.True(t, strings.HasSuffix(out, "\r\n\r\nhello world"))
+	})
+
+	t.Run("malformed chunked body", func(t *testing.T) {
+		resp := NewBaseResponse().
+			WithHeader("transfer-encoding", "chunked").
+			WithBody(strings.NewReader("zz\r\nhello\r\n0\r\n\r\n"))
+
+		_, _, err := ForHTTP10(resp, 1024)
+		assert.ErrorIs(t, err, errMalformedChunk)
+	})
+}
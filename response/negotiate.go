@@ -0,0 +1,232 @@
+package response
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shravanasati/shadowfax/request"
+)
+
+// Encoder writes v in the media type it's registered for. Encoders return an error wrapping
+// [ErrUnencodable] for values they can't represent, so that another acceptable media type is
+// tried.
+type Encoder func(w io.Writer, v any) error
+
+type negotiatedEncoder struct {
+	// contentType is the registered media type with its parameters, e.g. `text/csv; charset=utf-8`
+	contentType string
+	mediaType   string
+	params      map[string]string
+	encode      Encoder
+}
+
+// Negotiator picks the encoder of a response from the Accept header of the request.
+// The zero value has no encoders. It is safe for concurrent use.
+type Negotiator struct {
+	mu       sync.RWMutex
+	encoders []negotiatedEncoder
+}
+
+// NewNegotiator creates a Negotiator with the built-in encoders, in order of preference:
+// application/json, application/xml, text/csv and text/plain.
+func NewNegotiator() *Negotiator {
+	n := &Negotiator{}
+	n.Register("application/json", EncodeJSON)
+	n.Register("application/xml; charset=utf-8", EncodeXML)
+	n.Register("text/csv; charset=utf-8", EncodeCSV)
+	n.Register("text/plain; charset=utf-8", EncodeText)
+	return n
+}
+
+// Register adds an encoder for contentType, which is used as the Content-Type of the responses.
+// Registering a media type again replaces its encoder and keeps its preference, new media types
+// are the least preferred.
+func (n *Negotiator) Register(contentType string, enc Encoder) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || strings.Contains(mediaType, "*") || !strings.Contains(mediaType, "/") {
+		return fmt.Errorf("%w: %q", ErrInvalidMediaType, contentType)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	e := negotiatedEncoder{contentType: contentType, mediaType: mediaType, params: params, encode: enc}
+	i := slices.IndexFunc(n.encoders, func(e negotiatedEncoder) bool { return e.mediaType == mediaType })
+	if i >= 0 {
+		n.encoders[i] = e
+	} else {
+		n.encoders = append(n.encoders, e)
+	}
+	return nil
+}
+
+// MediaTypes returns the registered content types, in order of preference.
+func (n *Negotiator) MediaTypes() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	types := make([]string, len(n.encoders))
+	for i, e := range n.encoders {
+		types[i] = e.contentType
+	}
+	return types
+}
+
+// Negotiate encodes v in the media type the client prefers according to the Accept header of r,
+// as weighed by q-values. Ties are broken by the order of preference of the negotiator, which
+// is also used when the request has no Accept header. The response has a `Vary: Accept` header.
+//
+// If no acceptable encoder can represent v, the response is 406 Not Acceptable and lists the
+// available media types. Other encoding errors are returned.
+func (n *Negotiator) Negotiate(r *request.Request, v any) (Response, error) {
+	n.mu.RLock()
+	encoders := slices.Clone(n.encoders)
+	n.mu.RUnlock()
+
+	ranges := parseAccept(r.Headers.Get("accept"))
+	type candidate struct {
+		negotiatedEncoder
+		q float64
+	}
+	var candidates []candidate
+	for _, e := range encoders {
+		q := 1.0
+		if len(ranges) > 0 {
+			q = acceptQuality(ranges, e)
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{e, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		var buf bytes.Buffer
+		if err := c.encode(&buf, v); err != nil {
+			if errors.Is(err, ErrUnencodable) {
+				continue
+			}
+			return nil, err
+		}
+		return NewBaseResponse().
+			WithHeader("content-type", c.contentType).
+			WithHeader("content-length", strconv.Itoa(buf.Len())).
+			WithHeader("vary", "Accept").
+			WithBody(&buf), nil
+	}
+
+	var body strings.Builder
+	body.WriteString("Not Acceptable, available media types:\n")
+	for _, e := range encoders {
+		body.WriteString(e.contentType + "\n")
+	}
+	return NewTextResponse(body.String()).
+		WithHeader("vary", "Accept").
+		WithStatusCode(StatusNotAcceptable), nil
+}
+
+// DefaultNegotiator is the negotiator used by [Negotiate] and [RegisterEncoder].
+var DefaultNegotiator = NewNegotiator()
+
+// Negotiate encodes v with the [DefaultNegotiator], see [Negotiator.Negotiate].
+func Negotiate(r *request.Request, v any) (Response, error) {
+	return DefaultNegotiator.Negotiate(r, v)
+}
+
+// RegisterEncoder adds an encoder to the [DefaultNegotiator], see [Negotiator.Register].
+func RegisterEncoder(contentType string, enc Encoder) error {
+	return DefaultNegotiator.Register(contentType, enc)
+}
+
+// mediaRange is an element of an Accept header, e.g. `text/*;q=0.5`.
+type mediaRange struct {
+	typ, subtype string
+	params       map[string]string
+	q            float64
+}
+
+// parseAccept parses an Accept header, skipping invalid elements.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, elem := range strings.Split(header, ",") {
+		elem = strings.TrimSpace(elem)
+		if elem == "" {
+			continue
+		}
+
+		parts := strings.Split(elem, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(parts[0])), "/")
+		if !ok || typ == "" || subtype == "" || typ == "*" && subtype != "*" {
+			continue
+		}
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+
+		valid := true
+		for _, p := range parts[1:] {
+			key, value, _ := strings.Cut(p, "=")
+			key = strings.ToLower(strings.TrimSpace(key))
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			if key == "q" {
+				q, err := strconv.ParseFloat(value, 64)
+				if err != nil || q < 0 || q > 1 {
+					valid = false
+				}
+				mr.q = q
+				// parameters after the weight are accept extensions
+				break
+			}
+			if mr.params == nil {
+				mr.params = make(map[string]string)
+			}
+			mr.params[key] = value
+		}
+		if valid {
+			ranges = append(ranges, mr)
+		}
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of the most specific range matching the encoder, 0 if
+// none matches.
+func acceptQuality(ranges []mediaRange, e negotiatedEncoder) float64 {
+	typ, subtype, _ := strings.Cut(e.mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, mr := range ranges {
+		s := 0
+		switch {
+		case mr.typ == "*":
+		case mr.typ != typ:
+			continue
+		case mr.subtype == "*":
+			s = 1
+		case mr.subtype != subtype:
+			continue
+		default:
+			s = 2
+		}
+
+		matches := true
+		for k, v := range mr.params {
+			if !strings.EqualFold(e.params[k], v) {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		s += len(mr.params)
+
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+	return q
+}
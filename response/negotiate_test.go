@@ -0,0 +1,181 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBook struct {
+	Title     string    `json:"title" xml:"title" csv:"title"`
+	Pages     int       `json:"pages" xml:"pages" csv:"pages"`
+	Published time.Time `json:"-" xml:"-" csv:"published"`
+	secret    string
+}
+
+func (b testBook) String() string {
+	return fmt.Sprintf("%s (%d pages)", b.Title, b.Pages)
+}
+
+func newAcceptRequest(t *testing.T, accept ...string) *request.Request {
+	t.Helper()
+	raw := "GET /books HTTP/1.1\r\nHost: x\r\n"
+	for _, a := range accept {
+		raw += "Accept: " + a + "\r\n"
+	}
+	r, err := request.RequestFromReader(strings.NewReader(raw+"\r\n"), nil)
+	require.NoError(t, err)
+	return r
+}
+
+func readBody(t *testing.T, resp Response) string {
+	t.Helper()
+	body, err := io.ReadAll(resp.GetBody())
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestNegotiate(t *testing.T) {
+	book := testBook{Title: "The Hobbit", Pages: 310}
+
+	tests := []struct {
+		name        string
+		accept      []string
+		contentType string
+		body        string
+	}{
+		{"no accept header", nil, "application/json", `{"title":"The Hobbit","pages":310}`},
+		{"wildcard", []string{"*/*"}, "application/json", `{"title":"The Hobbit","pages":310}`},
+		{"exact", []string{"application/xml"}, "application/xml; charset=utf-8", xmlHeaderPrefix + "<testBook><title>The Hobbit</title><pages>310</pages></testBook>"},
+		{"q-values", []string{"application/json;q=0.5, text/plain"}, "text/plain; charset=utf-8", "The Hobbit (310 pages)"},
+		{"multiple header lines", []string{"application/json;q=0.1", "text/*;q=0.9"}, "text/plain; charset=utf-8", "The Hobbit (310 pages)"},
+		{"most specific range wins", []string{"text/*;q=0.8, text/plain;q=0, */*;q=0.1"}, "application/json", `{"title":"The Hobbit","pages":310}`},
+		{"media type parameters", []string{"text/plain;charset=UTF-8, application/json;q=0.5"}, "text/plain; charset=utf-8", "The Hobbit (310 pages)"},
+		{"unmatched parameters", []string{"text/plain;charset=latin1, application/json;q=0.5"}, "application/json", `{"title":"The Hobbit","pages":310}`},
+		{"invalid elements are skipped", []string{"nonsense, text/plain;q=2, application/xml"}, "application/xml; charset=utf-8", xmlHeaderPrefix + "<testBook><title>The Hobbit</title><pages>310</pages></testBook>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := Negotiate(newAcceptRequest(t, tt.accept...), book)
+			require.NoError(t, err)
+			assert.Equal(t, StatusOK, resp.GetStatusCode())
+			assert.Equal(t, tt.contentType, resp.GetHeaders().Get("content-type"))
+			assert.Equal(t, "Accept", resp.GetHeaders().Get("vary"))
+			body := readBody(t, resp)
+			assert.Equal(t, tt.body, body)
+			assert.Equal(t, fmt.Sprint(len(body)), resp.GetHeaders().Get("content-length"))
+		})
+	}
+}
+
+const xmlHeaderPrefix = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+func TestEncodeXMLSlices(t *testing.T) {
+	books := []testBook{{Title: "The Hobbit", Pages: 310}, {Title: "Beren and Lúthien", Pages: 288}}
+	var sb strings.Builder
+	require.NoError(t, EncodeXML(&sb, books))
+	assert.Equal(t, xmlHeaderPrefix+"<items><testBook><title>The Hobbit</title><pages>310</pages></testBook>"+
+		"<testBook><title>Beren and Lúthien</title><pages>288</pages></testBook></items>", sb.String())
+
+	sb.Reset()
+	require.NoError(t, EncodeXML(&sb, &[1]testBook{{Title: "a"}}))
+	assert.Equal(t, xmlHeaderPrefix+"<items><testBook><title>a</title><pages>0</pages></testBook></items>", sb.String())
+
+	sb.Reset()
+	require.NoError(t, EncodeXML(&sb, []testBook{}))
+	assert.Equal(t, xmlHeaderPrefix+"<items></items>", sb.String())
+
+	assert.ErrorIs(t, EncodeXML(io.Discard, []map[string]int{{"a": 1}}), ErrUnencodable)
+}
+
+func TestNegotiateFallsBackOnUnencodableValues(t *testing.T) {
+	// CSV can't encode a single book, and XML can't encode maps
+	resp, err := Negotiate(newAcceptRequest(t, "text/csv, application/json;q=0.5"), testBook{Title: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", resp.GetHeaders().Get("content-type"))
+
+	resp, err = Negotiate(newAcceptRequest(t, "application/xml"), map[string]int{"a": 1})
+	require.NoError(t, err)
+	assert.Equal(t, StatusNotAcceptable, resp.GetStatusCode())
+}
+
+func TestNegotiateNotAcceptable(t *testing.T) {
+	resp, err := Negotiate(newAcceptRequest(t, "image/png, application/json;q=0"), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, StatusNotAcceptable, resp.GetStatusCode())
+	assert.Equal(t, "Accept", resp.GetHeaders().Get("vary"))
+	body := readBody(t, resp)
+	for _, mediaType := range DefaultNegotiator.MediaTypes() {
+		assert.Contains(t, body, mediaType)
+	}
+}
+
+func TestNegotiateCustomEncoder(t *testing.T) {
+	n := NewNegotiator()
+	yaml := func(w io.Writer, v any) error {
+		b, ok := v.(testBook)
+		if !ok {
+			return ErrUnencodable
+		}
+		_, err := fmt.Fprintf(w, "title: %s\n", b.Title)
+		return err
+	}
+	require.NoError(t, n.Register("application/yaml", yaml))
+	assert.Equal(t, "application/yaml", n.MediaTypes()[4])
+
+	resp, err := n.Negotiate(newAcceptRequest(t, "application/yaml"), testBook{Title: "Silmarillion"})
+	require.NoError(t, err)
+	assert.Equal(t, "application/yaml", resp.GetHeaders().Get("content-type"))
+	assert.Equal(t, "title: Silmarillion\n", readBody(t, resp))
+
+	// replacing an encoder keeps its preference
+	failing := errors.New("boom")
+	require.NoError(t, n.Register("application/json", func(io.Writer, any) error { return failing }))
+	assert.Equal(t, "application/json", n.MediaTypes()[0])
+	_, err = n.Negotiate(newAcceptRequest(t), "x")
+	assert.ErrorIs(t, err, failing)
+
+	for _, invalid := range []string{"json", "text/*", "*/*", ""} {
+		assert.ErrorIs(t, n.Register(invalid, yaml), ErrInvalidMediaType, invalid)
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	published := time.Date(1937, 9, 21, 0, 0, 0, 0, time.UTC)
+	var b strings.Builder
+	require.NoError(t, EncodeCSV(&b, []*testBook{{Title: "The Hobbit, or There and Back Again", Pages: 310, Published: published}, nil}))
+	assert.Equal(t, "title,pages,published\n\"The Hobbit, or There and Back Again\",310,1937-09-21T00:00:00Z\n,,\n", b.String())
+
+	b.Reset()
+	require.NoError(t, EncodeCSV(&b, [][]string{{"a", "b"}, {"1", "2"}}))
+	assert.Equal(t, "a,b\n1,2\n", b.String())
+
+	for _, v := range []any{"text", []int{1}, map[string]string{}} {
+		assert.ErrorIs(t, EncodeCSV(io.Discard, v), ErrUnencodable)
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	for v, expected := range map[any]string{
+		"plain":                                "plain",
+		42:                                     "42",
+		true:                                   "true",
+		errors.New("failed"):                   "failed",
+		time.Duration(1500) * time.Millisecond: "1.5s",
+	} {
+		var b strings.Builder
+		require.NoError(t, EncodeText(&b, v))
+		assert.Equal(t, expected, b.String())
+	}
+
+	assert.ErrorIs(t, EncodeText(io.Discard, []string{"a"}), ErrUnencodable)
+	assert.ErrorIs(t, EncodeText(io.Discard, struct{}{}), ErrUnencodable)
+}
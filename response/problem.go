@@ -0,0 +1,129 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strconv"
+)
+
+// HTTPError is an error carrying an HTTP status, rendered as an RFC 9457 problem details object:
+//
+//	{"type": "https://example.com/probs/out-of-stock", "title": "Conflict", "status": 409,
+//	 "detail": "the ring is out of stock", "sku": "R-1"}
+type HTTPError struct {
+	// Status is the HTTP status code of the response. Statuses that aren't errors (4xx or
+	// 5xx), zero included, are sent as 500 Internal Server Error.
+	Status StatusCode
+
+	// Type is a URI identifying the problem type. Empty means `about:blank`.
+	Type string
+
+	// Title summarises the problem type. Defaults to the reason phrase of the status.
+	Title string
+
+	// Detail explains this occurrence of the problem.
+	Detail string
+
+	// Instance is a URI identifying this occurrence of the problem.
+	Instance string
+
+	// Extensions are additional members of the problem object. They can't override the
+	// members above.
+	Extensions map[string]any
+
+	// Err is the cause of the error. It is never sent to clients.
+	Err error
+}
+
+// NewHTTPError creates an HTTPError with a status and detail.
+func NewHTTPError(status StatusCode, detail string) *HTTPError {
+	return &HTTPError{Status: status, Detail: detail}
+}
+
+// WithExtension sets an extension member of the problem object.
+func (e *HTTPError) WithExtension(key string, value any) *HTTPError {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]any)
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+// WithCause sets the cause of the error, which is logged but never sent to clients.
+func (e *HTTPError) WithCause(err error) *HTTPError {
+	e.Err = err
+	return e
+}
+
+// ResponseStatus returns the status the error is sent with: Status, or else 500 Internal
+// Server Error if Status isn't a 4xx or 5xx status.
+func (e *HTTPError) ResponseStatus() StatusCode {
+	if e.Status < 400 || e.Status > 599 {
+		return StatusInternalServerError
+	}
+	return e.Status
+}
+
+// StatusTitle returns the title of the error, or else the reason phrase of its status.
+func (e *HTTPError) StatusTitle() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	return GetStatusReason(e.ResponseStatus())
+}
+
+func (e *HTTPError) Error() string {
+	msg := strconv.Itoa(int(e.Status)) + " " + e.StatusTitle()
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the error as a problem details object, with the extensions as top-level members.
+func (e *HTTPError) MarshalJSON() ([]byte, error) {
+	members := maps.Clone(e.Extensions)
+	if members == nil {
+		members = make(map[string]any)
+	}
+	for _, key := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, key)
+	}
+
+	if e.Type != "" {
+		members["type"] = e.Type
+	}
+	members["title"] = e.StatusTitle()
+	members["status"] = int(e.ResponseStatus())
+	if e.Detail != "" {
+		members["detail"] = e.Detail
+	}
+	if e.Instance != "" {
+		members["instance"] = e.Instance
+	}
+	return json.Marshal(members)
+}
+
+// NewProblemResponse creates an application/problem+json response from an HTTPError, with its
+// status (see [HTTPError.ResponseStatus]).
+func NewProblemResponse(e *HTTPError) (Response, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("encoding problem: %w", err)
+	}
+
+	return NewBaseResponse().
+		WithStatusCode(e.ResponseStatus()).
+		WithHeader("content-type", "application/problem+json").
+		WithHeader("content-length", strconv.Itoa(len(body))).
+		WithBody(bytes.NewReader(body)), nil
+}
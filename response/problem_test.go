@@ -0,0 +1,58 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPError(t *testing.T) {
+	cause := errors.New("sku R-1 has 0 units")
+	err := NewHTTPError(StatusConflict, "the ring is out of stock").
+		WithExtension("sku", "R-1").
+		WithExtension("status", "ignored").
+		WithCause(cause)
+	err.Type = "https://example.com/probs/out-of-stock"
+
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "409 Conflict: the ring is out of stock: sku R-1 has 0 units", err.Error())
+
+	body, marshalErr := json.Marshal(err)
+	require.NoError(t, marshalErr)
+	assert.JSONEq(t, `{"type": "https://example.com/probs/out-of-stock", "title": "Conflict", "status": 409,
+		"detail": "the ring is out of stock", "sku": "R-1"}`, string(body))
+
+	custom := &HTTPError{Status: StatusImATeapot, Title: "No coffee"}
+	assert.Equal(t, "418 No coffee", custom.Error())
+}
+
+func TestNewProblemResponse(t *testing.T) {
+	resp, err := NewProblemResponse(NewHTTPError(StatusNotFound, "no such book"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusNotFound, resp.GetStatusCode())
+	assert.Equal(t, "application/problem+json", resp.GetHeaders().Get("content-type"))
+	body := readBody(t, resp)
+	assert.JSONEq(t, `{"title": "Not Found", "status": 404, "detail": "no such book"}`, body)
+
+	_, err = NewProblemResponse(NewHTTPError(StatusBadRequest, "").WithExtension("bad", make(chan int)))
+	assert.Error(t, err)
+}
+
+func TestHTTPErrorResponseStatus(t *testing.T) {
+	for _, status := range []StatusCode{0, StatusOK, StatusFound, 600} {
+		e := &HTTPError{Status: status, Detail: "oops"}
+		assert.Equal(t, StatusInternalServerError, e.ResponseStatus())
+
+		body, err := json.Marshal(e)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"title": "Internal Server Error", "status": 500, "detail": "oops"}`, string(body))
+
+		resp, err := NewProblemResponse(e)
+		require.NoError(t, err)
+		assert.Equal(t, StatusInternalServerError, resp.GetStatusCode())
+	}
+	assert.Equal(t, StatusNotFound, NewHTTPError(StatusNotFound, "").ResponseStatus())
+}
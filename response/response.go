@@ -1,9 +1,11 @@
 package response
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 
+	"github.com/shravanasati/shadowfax/cookie"
 	"github.com/shravanasati/shadowfax/headers"
 )
 
@@ -30,16 +32,26 @@ type Response interface {
 
 	// WithBody sets the body of the response.
 	WithBody(io.Reader) Response
+
+	// WithCookie adds a Set-Cookie header for the cookie. Each cookie gets its own header line.
+	// Invalid cookies (see [cookie.Cookie.Valid]) are dropped, like invalid headers, to prevent
+	// header injection. Use [SetCookie] to get the validation error.
+	WithCookie(*cookie.Cookie) Response
 }
 
 // ResponseWriter is a writer for responses.
 type ResponseWriter struct {
-	conn  io.Writer
-	state responseState
+	conn    io.Writer
+	state   responseState
+	version string
 }
 
 func NewResponseWriter(conn io.Writer) *ResponseWriter {
-	return &ResponseWriter{conn: conn, state: newResponseState()}
+	version := "1.1"
+	if _, ok := conn.(*http10Writer); ok {
+		version = "1.0"
+	}
+	return &ResponseWriter{conn: conn, state: newResponseState(), version: version}
 }
 
 func (rw *ResponseWriter) WriteStatusLine(statusCode StatusCode) error {
@@ -49,7 +61,7 @@ func (rw *ResponseWriter) WriteStatusLine(statusCode StatusCode) error {
 	if rw.state != stateStatusLine {
 		return fmt.Errorf("%w: cannot write status line (state=%s)", ErrInvalidWriterState, rw.state)
 	}
-	_, err := fmt.Fprintf(rw.conn, "HTTP/1.1 %d %s\r\n", statusCode, GetStatusReason(statusCode))
+	_, err := fmt.Fprintf(rw.conn, "HTTP/%s %d %s\r\n", rw.version, statusCode, GetStatusReason(statusCode))
 	if err != nil {
 		return err
 	}
@@ -58,6 +70,36 @@ func (rw *ResponseWriter) WriteStatusLine(statusCode StatusCode) error {
 	return nil
 }
 
+// WriteInformational writes an informational (1xx) response, such as 103 Early Hints,
+// with its headers. Any number of informational responses can be written before the
+// status line of the final response.
+// 101 Switching Protocols isn't supported, and HTTP/1.0 clients don't understand 1xx responses.
+func (rw *ResponseWriter) WriteInformational(statusCode StatusCode, h *headers.Headers) error {
+	if rw.conn == nil {
+		return fmt.Errorf("(write informational) writer is nil")
+	}
+	if rw.state != stateStatusLine {
+		return fmt.Errorf("%w: cannot write informational response (state=%s)", ErrInvalidWriterState, rw.state)
+	}
+	if statusCode < 100 || statusCode > 199 || statusCode == StatusSwitchingProtocols {
+		return fmt.Errorf("%w: %d", ErrInvalidInformationalStatus, statusCode)
+	}
+	if rw.version == "1.0" {
+		return ErrInformationalUnsupported
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/%s %d %s\r\n", rw.version, statusCode, GetStatusReason(statusCode))
+	if h != nil {
+		for k, v := range h.Fields() {
+			fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+		}
+	}
+	buf.WriteString("\r\n")
+	_, err := rw.conn.Write(buf.Bytes())
+	return err
+}
+
 func (rw *ResponseWriter) WriteHeaders(h *headers.Headers) error {
 	if rw.conn == nil {
 		return fmt.Errorf("(write headers) writer is nil")
@@ -65,7 +107,7 @@ func (rw *ResponseWriter) WriteHeaders(h *headers.Headers) error {
 	if rw.state != stateHeaders {
 		return fmt.Errorf("%w: cannot write headers (state=%s)", ErrInvalidWriterState, rw.state)
 	}
-	for k, v := range h.All() {
+	for k, v := range h.Fields() {
 		fmt.Fprintf(rw.conn, "%s: %s\r\n", k, v)
 	}
 	rw.conn.Write([]byte("\r\n"))
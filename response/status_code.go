@@ -7,6 +7,7 @@ const (
 	StatusContinue           StatusCode = 100
 	StatusSwitchingProtocols StatusCode = 101
 	StatusProcessing         StatusCode = 102
+	StatusEarlyHints         StatusCode = 103
 
 	StatusOK                   StatusCode = 200
 	StatusCreated              StatusCode = 201
@@ -75,6 +76,7 @@ var reasonPhrases = map[StatusCode]string{
 	StatusContinue:           "Continue",
 	StatusSwitchingProtocols: "Switching Protocols",
 	StatusProcessing:         "Processing",
+	StatusEarlyHints:         "Early Hints",
 
 	StatusOK:                   "OK",
 	StatusCreated:              "Created",
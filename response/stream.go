@@ -2,6 +2,7 @@ package response
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"strings"
@@ -13,15 +14,29 @@ import (
 type TrailerSetter func(key, value string)
 
 // StreamFunc is a function that writes to a stream.
+// Writes to w fail once the stream's context is cancelled, stream functions that
+// block on anything other than w should also watch the context they were created with.
 type StreamFunc func(w io.Writer, setTrailer TrailerSetter) error
 
 // Reader returns a reader for the stream.
+// The stream function runs in its own goroutine, which is stopped when the reader is
+// closed or the stream's context is cancelled.
 func (sr *StreamResponse) Reader() io.Reader {
 	pr, pw := io.Pipe()
 
 	go func() {
 		defer pw.Close()
 
+		ctx := sr.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		stop := context.AfterFunc(ctx, func() {
+			// closing the read side makes pending and future writes fail with the cause
+			pr.CloseWithError(context.Cause(ctx))
+		})
+		defer stop()
+
 		setTrailer := func(key, value string) {
 			sr.Trailers.Add(key, value)
 		}
@@ -75,7 +90,7 @@ func (cr *chunkedReader) Read(p []byte) (int, error) {
 		cr.buf.WriteString("0\r\n")
 
 		if cr.trailers.Size() > 0 {
-			for key, value := range cr.trailers.All() {
+			for key, value := range cr.trailers.Fields() {
 				trailerLine := fmt.Sprintf("%s: %s\r\n", key, value)
 				cr.buf.WriteString(trailerLine)
 			}
@@ -92,22 +107,41 @@ func (cr *chunkedReader) Read(p []byte) (int, error) {
 	return 0, err
 }
 
+// Close closes the underlying reader if it is an [io.Closer].
+// For streams, this stops the stream function.
+func (cr *chunkedReader) Close() error {
+	if closer, ok := cr.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 // StreamResponse is a response that streams data.
 type StreamResponse struct {
 	Response
 	Stream      StreamFunc
 	trailerList []string
 	Trailers    *headers.Headers
+	ctx         context.Context
 }
 
-// NewStreamResponse creates a new stream response.
+// NewStreamResponse creates a new stream response. The server closes its body when the
+// client goes away, which makes writes to the stream fail.
 func NewStreamResponse(sf StreamFunc, trailers []string) *StreamResponse {
+	return NewStreamResponseWithContext(context.Background(), sf, trailers)
+}
+
+// NewStreamResponseWithContext creates a new stream response bound to ctx. When ctx is
+// cancelled, writes to the stream fail with the cancellation cause and the stream ends,
+// also when the response isn't written by the server.
+func NewStreamResponseWithContext(ctx context.Context, sf StreamFunc, trailers []string) *StreamResponse {
 	sr := &StreamResponse{
 		Response: NewBaseResponse().
 			WithHeader("transfer-encoding", "chunked"),
 		Stream:      sf,
 		trailerList: trailers,
 		Trailers:    headers.NewHeaders(),
+		ctx:         ctx,
 	}
 
 	if len(trailers) > 0 {
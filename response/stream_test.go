@@ -1,6 +1,7 @@
 package response
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -230,3 +231,61 @@ func TestChunkedReader(t *testing.T) {
 	// Should contain final chunk marker
 	assert.Contains(t, resultStr, "0\r\n")
 }
+
+func TestStreamResponseContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan error, 1)
+
+	streamFunc := func(w io.Writer, setTrailer TrailerSetter) error {
+		for {
+			if _, err := w.Write([]byte("tick\n")); err != nil {
+				finished <- err
+				return err
+			}
+		}
+	}
+
+	resp := NewStreamResponseWithContext(ctx, streamFunc, nil)
+
+	buf := make([]byte, 16)
+	_, err := resp.GetBody().Read(buf)
+	require.NoError(t, err)
+
+	cancel()
+
+	select {
+	case err := <-finished:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("stream function did not stop after context cancellation")
+	}
+
+	_, err = io.ReadAll(resp.GetBody())
+	assert.Error(t, err)
+}
+
+func TestStreamResponseStopsWhenBodyClosed(t *testing.T) {
+	finished := make(chan error, 1)
+
+	streamFunc := func(w io.Writer, setTrailer TrailerSetter) error {
+		for {
+			if _, err := w.Write([]byte("tick\n")); err != nil {
+				finished <- err
+				return err
+			}
+		}
+	}
+
+	resp := NewStreamResponse(streamFunc, nil)
+
+	closer, ok := resp.GetBody().(io.Closer)
+	require.True(t, ok)
+	require.NoError(t, closer.Close())
+
+	select {
+	case err := <-finished:
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+	case <-time.After(time.Second):
+		t.Fatal("stream function did not stop after body was closed")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
 
@@ -539,3 +540,35 @@ func TestIsMethodAllowedReturnsTrueWithCorsOptions(t *testing.T) {
 		t.Error("IsMethodAllowed should return true when c.allowedMethods is nil.")
 	}
 }
+
+func TestCorsHeadersKeepFields(t *testing.T) {
+	router := NewRouter(&RouterOptions{EnableCors: true})
+	router.Get("/foo", func(r *request.Request) response.Response {
+		return response.NewTextResponse("bar").WithHeader("Vary", "Accept")
+	})
+	handler := router.Handler()
+
+	httpReq, _ := http.NewRequest("GET", "http://example.com/foo", nil)
+	httpReq.Header.Add("Origin", "http://foo.com")
+	h := handler(convertRequest(httpReq)).GetHeaders()
+	if got, want := h.Values("Vary"), []string{"Accept", "Origin"}; !slices.Equal(got, want) {
+		t.Errorf("Vary values = %q, want %q", got, want)
+	}
+
+	httpReq, _ = http.NewRequest("OPTIONS", "http://example.com/foo", nil)
+	httpReq.Header.Add("Origin", "http://foo.com")
+	httpReq.Header.Add("Access-Control-Request-Method", "GET")
+	h = handler(convertRequest(httpReq)).GetHeaders()
+	if got, want := h.Values("Vary"), []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}; !slices.Equal(got, want) {
+		t.Errorf("preflight Vary values = %q, want %q", got, want)
+	}
+
+	h.SetPreserveCase(true)
+	var names []string
+	for name := range h.Fields() {
+		names = append(names, name)
+	}
+	if !slices.Contains(names, "Access-Control-Allow-Origin") {
+		t.Errorf("field names %q lost their case", names)
+	}
+}
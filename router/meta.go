@@ -0,0 +1,141 @@
+package router
+
+import (
+	"reflect"
+
+	"github.com/shravanasati/shadowfax/response"
+)
+
+// RouteMeta documents a route, see [Router.OpenAPI]. It is attached with [RouteOption]s when
+// registering the route.
+type RouteMeta struct {
+	Summary     string
+	Description string
+	OperationID string
+	Tags        []string
+	Deprecated  bool
+
+	// Hidden routes are left out of the OpenAPI document.
+	Hidden bool
+
+	// Params are the query, header, cookie and path parameters. Path parameters that aren't
+	// listed are documented as strings.
+	Params []ParamMeta
+
+	// Body is the type of the JSON request body, nil if the route has no body.
+	Body reflect.Type
+
+	// Responses are keyed by status code.
+	Responses map[response.StatusCode]ResponseMeta
+}
+
+// ParamMeta documents a parameter of a route.
+type ParamMeta struct {
+	// In is where the parameter is found: "path", "query", "header" or "cookie".
+	In          string
+	Name        string
+	Description string
+	Required    bool
+	// Type is the type of the parameter, a string if nil.
+	Type reflect.Type
+}
+
+// ResponseMeta documents a response of a route.
+type ResponseMeta struct {
+	Description string
+	// Type is the type of the JSON body, nil for responses without a body.
+	Type reflect.Type
+}
+
+// RouteOption sets metadata of a route when registering it.
+type RouteOption func(*RouteMeta)
+
+// Summary sets a short summary of what the route does.
+func Summary(summary string) RouteOption {
+	return func(m *RouteMeta) { m.Summary = summary }
+}
+
+// Description sets a longer description of the route.
+func Description(description string) RouteOption {
+	return func(m *RouteMeta) { m.Description = description }
+}
+
+// OperationID sets the unique identifier of the route, used by code generators.
+func OperationID(id string) RouteOption {
+	return func(m *RouteMeta) { m.OperationID = id }
+}
+
+// Tags groups the route under tags.
+func Tags(tags ...string) RouteOption {
+	return func(m *RouteMeta) { m.Tags = append(m.Tags, tags...) }
+}
+
+// Deprecated marks the route as deprecated.
+func Deprecated() RouteOption {
+	return func(m *RouteMeta) { m.Deprecated = true }
+}
+
+// Hidden leaves the route out of the OpenAPI document.
+func Hidden() RouteOption {
+	return func(m *RouteMeta) { m.Hidden = true }
+}
+
+// Param documents a parameter. The type of example is the type of the parameter, e.g.
+// `Param("query", "page", 0, "page number")` documents an integer.
+func Param(in, name string, example any, description string) RouteOption {
+	return func(m *RouteMeta) {
+		m.Params = append(m.Params, ParamMeta{
+			In:          in,
+			Name:        name,
+			Description: description,
+			Required:    in == "path",
+			Type:        reflect.TypeOf(example),
+		})
+	}
+}
+
+// Body documents the JSON request body, with the type of example.
+func Body(example any) RouteOption {
+	return func(m *RouteMeta) { m.Body = reflect.TypeOf(example) }
+}
+
+// Returns documents a response. The type of example is the type of its JSON body, nil for
+// responses without a body.
+func Returns(status response.StatusCode, example any, description string) RouteOption {
+	return func(m *RouteMeta) {
+		if m.Responses == nil {
+			m.Responses = make(map[response.StatusCode]ResponseMeta)
+		}
+		m.Responses[status] = ResponseMeta{Description: description, Type: reflect.TypeOf(example)}
+	}
+}
+
+// routeKey identifies a route in the metadata of the router.
+func routeKey(method, path string) string {
+	return method + " " + normalizePattern(path)
+}
+
+// setMeta replaces the metadata of a route with base, if any, updated by opts.
+func (r *Router) setMeta(method, path string, base *RouteMeta, opts []RouteOption) {
+	key := routeKey(method, path)
+	if base == nil && len(opts) == 0 {
+		delete(r.meta, key)
+		return
+	}
+	if base == nil {
+		base = &RouteMeta{}
+	}
+	for _, opt := range opts {
+		opt(base)
+	}
+	r.meta[key] = base
+}
+
+// RouteMeta returns the metadata of the route registered for method and path.
+func (r *Router) RouteMeta(method, path string) (RouteMeta, bool) {
+	meta, ok := r.meta[routeKey(method, path)]
+	if !ok {
+		return RouteMeta{}, false
+	}
+	return *meta, true
+}
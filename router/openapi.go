@@ -0,0 +1,550 @@
+package router
+
+import (
+	"encoding"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/shravanasati/shadowfax/server"
+	"github.com/shravanasati/shadowfax/validate"
+)
+
+// OpenAPIInfo describes the API in an OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIDocument is an OpenAPI 3.1 document, see [Router.OpenAPI].
+type OpenAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components *Components                      `json:"components,omitempty"`
+}
+
+// Components holds the schemas referenced by the operations of an [OpenAPIDocument].
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Operation is a route of an [OpenAPIDocument].
+type Operation struct {
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	OperationID string                      `json:"operationId,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*Parameter                `json:"parameters,omitempty"`
+	RequestBody *RequestBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+}
+
+// Parameter is a parameter of an [Operation].
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the request body of an [Operation].
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// OpenAPIResponse is a response of an [Operation].
+type OpenAPIResponse struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a body in a given media type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema, generated from Go types.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+// openAPIMethods maps the methods OpenAPI can describe to their operation keys.
+var openAPIMethods = map[string]string{
+	"GET":     "get",
+	"PUT":     "put",
+	"POST":    "post",
+	"DELETE":  "delete",
+	"OPTIONS": "options",
+	"HEAD":    "head",
+	"PATCH":   "patch",
+	"TRACE":   "trace",
+}
+
+// paramSources are the binding tags documented as parameters.
+var paramSources = []string{"path", "query", "header", "cookie"}
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	httpErrorType       = reflect.TypeFor[response.HTTPError]()
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// OpenAPI generates an OpenAPI 3.1 document describing the routes of the router, with their
+// [RouteMeta]. Routes registered with [HandleTyped] are also described by their input and output
+// types: parameters come from the binding tags of the input, the request body from its other
+// fields, and schemas are constrained by validate tags.
+//
+// Path parameters are written as `{id}`. Wildcards are documented as path parameters too,
+// although they match several segments. Methods OpenAPI can't describe, such as PROPFIND, are
+// left out, as are routes registered with [Router.Handle]: they match any method and their
+// handler is opaque, even when it is the handler of another router.
+func (r *Router) OpenAPI(info OpenAPIInfo) *OpenAPIDocument {
+	g := &schemaGenerator{schemas: map[string]*Schema{}, names: map[reflect.Type]string{}}
+	doc := &OpenAPIDocument{OpenAPI: "3.1.0", Info: info, Paths: map[string]map[string]*Operation{}}
+
+	for _, method := range slices.Sorted(maps.Keys(r.trees)) {
+		key, ok := openAPIMethods[method]
+		if !ok {
+			continue
+		}
+		r.trees[method].Walk(func(pattern string, _ server.Handler) {
+			meta := r.meta[method+" "+pattern]
+			if meta != nil && meta.Hidden {
+				return
+			}
+			var types *RouteTypes
+			if t, ok := r.types[method+" "+pattern]; ok {
+				types = &t
+			}
+
+			path, pathParams := openAPIPath(pattern)
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = map[string]*Operation{}
+			}
+			doc.Paths[path][key] = g.operation(method, pathParams, meta, types)
+		})
+	}
+
+	if len(g.schemas) > 0 {
+		doc.Components = &Components{Schemas: g.schemas}
+	}
+	return doc
+}
+
+// OpenAPIHandler returns a handler serving the OpenAPI document of the router as JSON. The
+// document is generated for every request, so it includes routes registered later on. Register
+// it with the [Hidden] option to leave it out of the document.
+func (r *Router) OpenAPIHandler(info OpenAPIInfo) server.Handler {
+	return func(req *request.Request) response.Response {
+		resp, err := response.NewJSONResponse(r.OpenAPI(info))
+		if err != nil {
+			return response.
+				NewTextResponse(response.GetStatusReason(response.StatusInternalServerError)).
+				WithStatusCode(response.StatusInternalServerError)
+		}
+		return resp
+	}
+}
+
+// pathParam is a parameter in a route pattern.
+type pathParam struct {
+	name     string
+	wildcard bool
+}
+
+// openAPIPath converts a route pattern into an OpenAPI path, e.g. `/files/:id/*rest` into
+// `/files/{id}/{rest}`.
+func openAPIPath(pattern string) (string, []pathParam) {
+	var params []pathParam
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			params = append(params, pathParam{name: segment[1:]})
+		case segment == "*":
+			// unnamed wildcards still need a name in the document
+			params = append(params, pathParam{name: "wildcard", wildcard: true})
+		case strings.HasPrefix(segment, "*"):
+			params = append(params, pathParam{name: segment[1:], wildcard: true})
+		default:
+			continue
+		}
+		segments[i] = "{" + params[len(params)-1].name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// schemaGenerator generates schemas, collecting the schemas of named structs as components.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func (g *schemaGenerator) operation(method string, pathParams []pathParam, meta *RouteMeta, types *RouteTypes) *Operation {
+	var m RouteMeta
+	if meta != nil {
+		m = *meta
+	}
+	op := &Operation{
+		Summary:     m.Summary,
+		Description: m.Description,
+		OperationID: m.OperationID,
+		Tags:        m.Tags,
+		Deprecated:  m.Deprecated,
+		Responses:   map[string]*OpenAPIResponse{},
+	}
+
+	// parameters of the typed input, overridden by the documented ones
+	var params []*Parameter
+	if types != nil {
+		params = g.inputParams(types.In)
+	}
+	for _, pm := range m.Params {
+		p := &Parameter{Name: pm.Name, In: pm.In, Description: pm.Description, Required: pm.Required, Schema: &Schema{Type: "string"}}
+		if pm.Type != nil {
+			p.Schema = g.schema(pm.Type)
+		}
+		i := slices.IndexFunc(params, func(q *Parameter) bool { return q.In == p.In && q.Name == p.Name })
+		if i >= 0 {
+			params[i] = p
+		} else {
+			params = append(params, p)
+		}
+	}
+
+	// path parameters come first, in the order of the path
+	for _, pp := range pathParams {
+		i := slices.IndexFunc(params, func(q *Parameter) bool { return q.In == "path" && q.Name == pp.name })
+		p := &Parameter{Name: pp.name, In: "path", Schema: &Schema{Type: "string"}}
+		if i >= 0 {
+			p = params[i]
+			params = slices.Delete(params, i, i+1)
+		}
+		p.Required = true
+		if pp.wildcard && p.Description == "" {
+			p.Description = "The rest of the path, slashes included."
+		}
+		op.Parameters = append(op.Parameters, p)
+	}
+	op.Parameters = append(op.Parameters, params...)
+
+	switch {
+	case m.Body != nil:
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.schema(m.Body))}
+	case types != nil && method != "GET" && method != "HEAD" && method != "DELETE" && method != "OPTIONS":
+		if body := g.structSchema(types.In, true); len(body.Properties) > 0 {
+			op.RequestBody = &RequestBody{Required: true, Content: jsonContent(body)}
+		}
+	}
+
+	hasSuccess := false
+	for status, rm := range m.Responses {
+		resp := &OpenAPIResponse{Description: rm.Description}
+		if resp.Description == "" {
+			resp.Description = response.GetStatusReason(status)
+		}
+		if rm.Type != nil {
+			resp.Content = jsonContent(g.schema(rm.Type))
+		}
+		op.Responses[strconv.Itoa(int(status))] = resp
+		hasSuccess = hasSuccess || status >= 200 && status < 300
+	}
+	if types != nil {
+		if !hasSuccess {
+			op.Responses["200"] = &OpenAPIResponse{Description: "OK", Content: jsonContent(g.schema(types.Out))}
+		}
+		if _, ok := op.Responses["default"]; !ok {
+			op.Responses["default"] = &OpenAPIResponse{
+				Description: "Error",
+				Content:     map[string]*MediaType{"application/problem+json": {Schema: g.schema(httpErrorType)}},
+			}
+		}
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = &OpenAPIResponse{Description: "OK"}
+	}
+	return op
+}
+
+func jsonContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
+
+// inputParams returns the parameters bound into the fields of a typed handler input.
+func (g *schemaGenerator) inputParams(t reflect.Type) []*Parameter {
+	var params []*Parameter
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		source, name := "", ""
+		for _, s := range paramSources {
+			if tag, ok := f.Tag.Lookup(s); ok {
+				source, name = s, tag
+				break
+			}
+		}
+		if source == "" {
+			if f.Type.Kind() == reflect.Struct && !isSchemaLeaf(f.Type) && !hasBindingTag(f, "form") {
+				params = append(params, g.inputParams(f.Type)...)
+			}
+			continue
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		rules := validate.ParseTag(f.Tag.Get("validate"))
+		schema := g.schema(f.Type)
+		applyRules(schema, rules)
+		params = append(params, &Parameter{
+			Name:     name,
+			In:       source,
+			Required: source == "path" || hasRule(rules, "required"),
+			Schema:   schema,
+		})
+	}
+	return params
+}
+
+func hasBindingTag(f reflect.StructField, sources ...string) bool {
+	for _, s := range slices.Concat(paramSources, sources) {
+		if _, ok := f.Tag.Lookup(s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRule(rules []validate.TagRule, name string) bool {
+	return slices.ContainsFunc(rules, func(r validate.TagRule) bool { return r.Name == name })
+}
+
+// isSchemaLeaf reports whether values of type t are encoded as a single JSON value rather than
+// an object of their fields.
+func isSchemaLeaf(t reflect.Type) bool {
+	return t == timeType ||
+		t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+var componentNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// schema returns the schema of values of type t. Named structs are referenced as components.
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == httpErrorType:
+		return g.component(t, func() *Schema {
+			return &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"type":     {Type: "string", Format: "uri-reference"},
+					"title":    {Type: "string"},
+					"status":   {Type: "integer"},
+					"detail":   {Type: "string"},
+					"instance": {Type: "string", Format: "uri-reference"},
+				},
+			}
+		})
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// any JSON value
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t, false)
+		}
+		return g.component(t, func() *Schema { return g.structSchema(t, false) })
+	}
+	// interfaces and other types can hold any value
+	return &Schema{}
+}
+
+// component returns a reference to the component schema of t, generating it on first use.
+func (g *schemaGenerator) component(t reflect.Type, generate func() *Schema) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		base := componentNameRegex.ReplaceAllString(t.Name(), "_")
+		name = base
+		for i := 2; g.schemas[name] != nil; i++ {
+			name = base + strconv.Itoa(i)
+		}
+		// registered before generating, for recursive types
+		g.names[t] = name
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *generate()
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// structSchema returns the object schema of struct type t. Fields filled from parameters are
+// skipped if skipParams is set.
+func (g *schemaGenerator) structSchema(t reflect.Type, skipParams bool) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(s, t, skipParams)
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type, skipParams bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || skipParams && hasBindingTag(f, "form") {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct && !isSchemaLeaf(ft) {
+			// promoted fields
+			g.addFields(s, ft, skipParams)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := g.schema(f.Type)
+		if opts == "string" && prop.Type != "" && prop.Type != "object" && prop.Type != "array" {
+			prop = &Schema{Type: "string"}
+		}
+		rules := validate.ParseTag(f.Tag.Get("validate"))
+		applyRules(prop, rules)
+		s.Properties[name] = prop
+		if hasRule(rules, "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// applyRules constrains a schema with validation rules.
+func applyRules(s *Schema, rules []validate.TagRule) {
+	if s.Ref != "" {
+		return
+	}
+
+	for _, r := range rules {
+		switch r.Name {
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(r.Param, 64)
+			if err != nil {
+				continue
+			}
+			setMin, setMax := r.Name != "max", r.Name != "min"
+			switch s.Type {
+			case "string":
+				if setMin {
+					s.MinLength = ptr(int(n))
+				}
+				if setMax {
+					s.MaxLength = ptr(int(n))
+				}
+			case "array":
+				if setMin {
+					s.MinItems = ptr(int(n))
+				}
+				if setMax {
+					s.MaxItems = ptr(int(n))
+				}
+			case "integer", "number":
+				if setMin {
+					s.Minimum = ptr(n)
+				}
+				if setMax {
+					s.Maximum = ptr(n)
+				}
+			}
+		case "oneof":
+			s.Enum = nil
+			for _, option := range strings.Fields(r.Param) {
+				if s.Type == "integer" || s.Type == "number" {
+					if n, err := strconv.ParseFloat(option, 64); err == nil {
+						s.Enum = append(s.Enum, n)
+					}
+				} else {
+					s.Enum = append(s.Enum, option)
+				}
+			}
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "regex":
+			s.Pattern = r.Param
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,189 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type apiAuthor struct {
+	Name  string `json:"name" validate:"required,max=100"`
+	Email string `json:"email,omitempty" validate:"omitempty,email"`
+}
+
+type apiBook struct {
+	ID        int64      `json:"id"`
+	Title     string     `json:"title"`
+	Tags      []string   `json:"tags" validate:"max=5"`
+	Author    *apiAuthor `json:"author"`
+	Related   []apiBook  `json:"related,omitempty"`
+	Published time.Time  `json:"published"`
+	Internal  string     `json:"-"`
+}
+
+type apiListBooks struct {
+	Paging
+	Shelf string `path:"shelf" validate:"oneof=fiction poetry"`
+	Sort  string `query:"sort" validate:"omitempty,oneof=title date"`
+	Token string `header:"X-Token" validate:"required"`
+}
+
+type Paging struct {
+	Page int `query:"page" validate:"omitempty,min=1"`
+}
+
+type apiCreateBook struct {
+	Shelf  string    `path:"shelf" json:"-"`
+	Title  string    `json:"title" validate:"required,min=1,max=200"`
+	Pages  int       `json:"pages" validate:"min=1"`
+	Format string    `json:"format" validate:"regex=^(paper|ebook)$"`
+	Author apiAuthor `json:"author"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	router := NewRouter(nil)
+	noop := func(r *request.Request) response.Response { return response.NewTextResponse("") }
+
+	router.Get("/health", noop, Summary("Health check"), Tags("ops"), Returns(response.StatusNoContent, nil, ""))
+	router.Get("/files/*path", noop, Summary("Serve files"))
+	router.Get("/legacy", noop, Deprecated(), Hidden())
+	router.Method("PROPFIND", "/dav", noop)
+	router.Delete("/books/:id", noop,
+		OperationID("deleteBook"),
+		Param("query", "force", false, "delete even if borrowed"),
+		Param("path", "id", int64(0), "book id"),
+		Returns(response.StatusNoContent, nil, "deleted"),
+		Returns(response.StatusNotFound, response.HTTPError{}, ""))
+	router.Put("/books/:id", noop, Body(apiBook{}), Returns(response.StatusOK, apiBook{}, "the updated book"))
+	HandleTyped(router, "GET", "/shelves/:shelf/books", func(r *request.Request, in apiListBooks) ([]apiBook, error) { return nil, nil },
+		Tags("books"))
+	HandleTyped(router, "POST", "/shelves/:shelf/books", func(r *request.Request, in apiCreateBook) (*apiBook, error) { return nil, nil })
+	router.Get("/openapi.json", router.OpenAPIHandler(OpenAPIInfo{Title: "Library", Version: "1.0.0"}), Hidden())
+
+	res, body := serveTyped(t, router.Handler(), "GET", "/openapi.json", "", "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal([]byte(body), &doc))
+	assert.Equal(t, "3.1.0", doc["openapi"])
+	assert.Equal(t, map[string]any{"title": "Library", "version": "1.0.0"}, doc["info"])
+
+	paths := doc["paths"].(map[string]any)
+	var pathNames []string
+	for p := range paths {
+		pathNames = append(pathNames, p)
+	}
+	assert.ElementsMatch(t, []string{"/health", "/files/{path}", "/books/{id}", "/shelves/{shelf}/books"}, pathNames)
+
+	op := func(path, method string) string {
+		b, err := json.Marshal(paths[path].(map[string]any)[method])
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	assert.JSONEq(t, `{"summary": "Health check", "tags": ["ops"], "responses": {"204": {"description": "No Content"}}}`, op("/health", "get"))
+	assert.JSONEq(t, `{"summary": "Serve files", "parameters": [
+		{"name": "path", "in": "path", "required": true, "description": "The rest of the path, slashes included.", "schema": {"type": "string"}}
+	], "responses": {"200": {"description": "OK"}}}`, op("/files/{path}", "get"))
+
+	assert.JSONEq(t, `{"operationId": "deleteBook", "parameters": [
+		{"name": "id", "in": "path", "required": true, "description": "book id", "schema": {"type": "integer", "format": "int64"}},
+		{"name": "force", "in": "query", "description": "delete even if borrowed", "schema": {"type": "boolean"}}
+	], "responses": {
+		"204": {"description": "deleted"},
+		"404": {"description": "Not Found", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/HTTPError"}}}}
+	}}`, op("/books/{id}", "delete"))
+
+	assert.JSONEq(t, `{"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+		"requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/apiBook"}}}},
+		"responses": {"200": {"description": "the updated book", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/apiBook"}}}}}
+	}`, op("/books/{id}", "put"))
+
+	problem := `"default": {"description": "Error", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/HTTPError"}}}}`
+	assert.JSONEq(t, `{"tags": ["books"], "parameters": [
+		{"name": "shelf", "in": "path", "required": true, "schema": {"type": "string", "enum": ["fiction", "poetry"]}},
+		{"name": "page", "in": "query", "schema": {"type": "integer", "format": "int64", "minimum": 1}},
+		{"name": "sort", "in": "query", "schema": {"type": "string", "enum": ["title", "date"]}},
+		{"name": "X-Token", "in": "header", "required": true, "schema": {"type": "string"}}
+	], "responses": {
+		"200": {"description": "OK", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/apiBook"}}}}},
+		`+problem+`
+	}}`, op("/shelves/{shelf}/books", "get"))
+
+	assert.JSONEq(t, `{"parameters": [{"name": "shelf", "in": "path", "required": true, "schema": {"type": "string"}}],
+		"requestBody": {"required": true, "content": {"application/json": {"schema": {"type": "object", "required": ["title"], "properties": {
+			"title": {"type": "string", "minLength": 1, "maxLength": 200},
+			"pages": {"type": "integer", "format": "int64", "minimum": 1},
+			"format": {"type": "string", "pattern": "^(paper|ebook)$"},
+			"author": {"$ref": "#/components/schemas/apiAuthor"}
+		}}}}},
+		"responses": {
+			"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/apiBook"}}}},
+			`+problem+`
+		}
+	}`, op("/shelves/{shelf}/books", "post"))
+
+	schemas, err := json.Marshal(doc["components"].(map[string]any)["schemas"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"apiAuthor": {"type": "object", "required": ["name"], "properties": {
+			"name": {"type": "string", "maxLength": 100},
+			"email": {"type": "string", "format": "email"}
+		}},
+		"apiBook": {"type": "object", "properties": {
+			"id": {"type": "integer", "format": "int64"},
+			"title": {"type": "string"},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 5},
+			"author": {"$ref": "#/components/schemas/apiAuthor"},
+			"related": {"type": "array", "items": {"$ref": "#/components/schemas/apiBook"}},
+			"published": {"type": "string", "format": "date-time"}
+		}},
+		"HTTPError": {"type": "object", "properties": {
+			"type": {"type": "string", "format": "uri-reference"},
+			"title": {"type": "string"},
+			"status": {"type": "integer"},
+			"detail": {"type": "string"},
+			"instance": {"type": "string", "format": "uri-reference"}
+		}}
+	}`, string(schemas))
+}
+
+func TestRouteMeta(t *testing.T) {
+	router := NewRouter(nil)
+	noop := func(r *request.Request) response.Response { return response.NewTextResponse("") }
+
+	router.Get("/books/:id/", noop, Summary("Get a book"), Tags("books"), Tags("public"))
+	meta, ok := router.RouteMeta("GET", "/books/:id")
+	require.True(t, ok)
+	assert.Equal(t, "Get a book", meta.Summary)
+	assert.Equal(t, []string{"books", "public"}, meta.Tags)
+
+	// registering the route again replaces its metadata
+	router.Get("/books/:id", noop)
+	_, ok = router.RouteMeta("GET", "/books/:id")
+	assert.False(t, ok)
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		params        []pathParam
+	}{
+		{"/", "/", nil},
+		{"/users/:id/posts/:post", "/users/{id}/posts/{post}", []pathParam{{name: "id"}, {name: "post"}}},
+		{"/static/*filepath", "/static/{filepath}", []pathParam{{name: "filepath", wildcard: true}}},
+		{"/static/*", "/static/{wildcard}", []pathParam{{name: "wildcard", wildcard: true}}},
+	}
+	for _, tt := range tests {
+		path, params := openAPIPath(tt.pattern)
+		assert.Equal(t, tt.path, path)
+		assert.Equal(t, tt.params, params)
+	}
+}
@@ -1,6 +1,12 @@
 package router
 
+import "github.com/shravanasati/shadowfax/server"
+
 type RouterOptions struct {
 	EnableCors  bool
 	CorsOptions CorsOptions
+
+	// ErrorRenderer renders the errors of typed handlers, see [HandleTyped].
+	// Defaults to [server.DefaultErrorRenderer].
+	ErrorRenderer *server.ErrorRenderer
 }
@@ -1,8 +1,11 @@
 package router
 
 import (
-	"maps"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/shravanasati/shadowfax/headers"
 	"github.com/shravanasati/shadowfax/request"
 	"github.com/shravanasati/shadowfax/response"
 	"github.com/shravanasati/shadowfax/server"
@@ -19,77 +22,133 @@ type Middleware func(server.Handler) server.Handler
 // Router is a simple HTTP router.
 type Router struct {
 	trees           map[string]*TrieNode
+	anyTree         *TrieNode
 	notFoundHandler server.Handler
 	middlewares     []Middleware
 	corsEnabled     bool
 	cors            *corsHandler
+	renderer        *server.ErrorRenderer
+	// types and handler names of the routes registered with HandleTyped and metadata of the
+	// routes, keyed by routeKey
+	types map[string]RouteTypes
+	names map[string]string
+	meta  map[string]*RouteMeta
 }
 
 // Creates a new router.
 func NewRouter(opts *RouterOptions) *Router {
-	methodTreeMap := map[string]*TrieNode{
-		"GET":     NewTrieNode(),
-		"POST":    NewTrieNode(),
-		"PUT":     NewTrieNode(),
-		"PATCH":   NewTrieNode(),
-		"DELETE":  NewTrieNode(),
-		"OPTIONS": NewTrieNode(),
-		"HEAD":    NewTrieNode(),
-		"ANY":     NewTrieNode(),
-	}
-
 	router := &Router{
-		trees:           methodTreeMap,
+		trees:           map[string]*TrieNode{},
+		anyTree:         NewTrieNode(),
 		notFoundHandler: defaultNotFoundHandler,
 		middlewares:     []Middleware{},
+		types:           map[string]RouteTypes{},
+		names:           map[string]string{},
+		meta:            map[string]*RouteMeta{},
 	}
 
 	if opts != nil && opts.EnableCors {
 		router.corsEnabled = true
 		router.cors = newCorsHandler(opts.CorsOptions)
 	}
+	if opts != nil {
+		router.renderer = opts.ErrorRenderer
+	}
 
 	return router
 }
 
+// Method registers a new route for the given HTTP method. Any method token is accepted,
+// including extension methods such as PROPFIND, MKCOL or QUERY. Methods are case-sensitive.
+// [AnyMethod] registers the route for any method, like [Router.Handle].
+// Options attach metadata documenting the route, see [RouteMeta].
+// It panics if method isn't a valid token.
+func (r *Router) Method(method, path string, handler server.Handler, opts ...RouteOption) {
+	r.method(method, path, handler)
+	delete(r.types, routeKey(method, path))
+	delete(r.names, routeKey(method, path))
+	r.setMeta(method, path, nil, opts)
+}
+
+func (r *Router) method(method, path string, handler server.Handler) {
+	if !request.IsValidMethod(method) {
+		panic("router: invalid method " + strconv.Quote(method))
+	}
+	if method == AnyMethod {
+		r.anyTree.AddRoute(path, handler)
+		return
+	}
+	tree, ok := r.trees[method]
+	if !ok {
+		tree = NewTrieNode()
+		r.trees[method] = tree
+	}
+	tree.AddRoute(path, handler)
+}
+
 // Get registers a new GET route.
-func (r *Router) Get(path string, handler server.Handler) {
-	r.trees["GET"].AddRoute(path, handler)
+func (r *Router) Get(path string, handler server.Handler, opts ...RouteOption) {
+	r.Method("GET", path, handler, opts...)
 }
 
 // Post registers a new POST route.
-func (r *Router) Post(path string, handler server.Handler) {
-	r.trees["POST"].AddRoute(path, handler)
+func (r *Router) Post(path string, handler server.Handler, opts ...RouteOption) {
+	r.Method("POST", path, handler, opts...)
 }
 
 // Put registers a new PUT route.
-func (r *Router) Put(path string, handler server.Handler) {
-	r.trees["PUT"].AddRoute(path, handler)
+func (r *Router) Put(path string, handler server.Handler, opts ...RouteOption) {
+	r.Method("PUT", path, handler, opts...)
 }
 
 // Patch registers a new PATCH route.
-func (r *Router) Patch(path string, handler server.Handler) {
-	r.trees["PATCH"].AddRoute(path, handler)
+func (r *Router) Patch(path string, handler server.Handler, opts ...RouteOption) {
+	r.Method("PATCH", path, handler, opts...)
 }
 
 // Delete registers a new DELETE route.
-func (r *Router) Delete(path string, handler server.Handler) {
-	r.trees["DELETE"].AddRoute(path, handler)
+func (r *Router) Delete(path string, handler server.Handler, opts ...RouteOption) {
+	r.Method("DELETE", path, handler, opts...)
 }
 
 // Options registers a new OPTIONS route.
-func (r *Router) Options(path string, handler server.Handler) {
-	r.trees["OPTIONS"].AddRoute(path, handler)
+func (r *Router) Options(path string, handler server.Handler, opts ...RouteOption) {
+	r.Method("OPTIONS", path, handler, opts...)
 }
 
 // Head registers a new HEAD route.
-func (r *Router) Head(path string, handler server.Handler) {
-	r.trees["HEAD"].AddRoute(path, handler)
+func (r *Router) Head(path string, handler server.Handler, opts ...RouteOption) {
+	r.Method("HEAD", path, handler, opts...)
 }
 
 // Handle registers a new route for any HTTP method.
 func (r *Router) Handle(path string, handler server.Handler) {
-	r.trees["ANY"].AddRoute(path, handler)
+	r.anyTree.AddRoute(path, handler)
+}
+
+// match finds the handler registered for the method and path.
+func (r *Router) match(method, path string) (server.Handler, map[string]string) {
+	tree, ok := r.trees[method]
+	if !ok {
+		return nil, nil
+	}
+	return tree.Match(path)
+}
+
+// allowedMethods returns the sorted methods with a route matching path.
+func (r *Router) allowedMethods(path string) []string {
+	var methods []string
+	for method, tree := range r.trees {
+		if handler, _ := tree.Match(path); handler != nil {
+			methods = append(methods, method)
+		}
+	}
+	if slices.Contains(methods, "GET") && !slices.Contains(methods, "HEAD") {
+		// HEAD requests are served by GET routes
+		methods = append(methods, "HEAD")
+	}
+	slices.Sort(methods)
+	return methods
 }
 
 // NotFound sets the handler for when no route is found.
@@ -102,6 +161,14 @@ func (r *Router) Use(m ...Middleware) {
 	r.middlewares = append(r.middlewares, m...)
 }
 
+// setHeaders replaces the fields of dst that are in src, see [headers.Headers.AddAll].
+func setHeaders(dst, src *headers.Headers) {
+	for name := range src.All() {
+		dst.Remove(name)
+	}
+	dst.AddAll(src)
+}
+
 func (r *Router) chain(h server.Handler) server.Handler {
 	for i := len(r.middlewares) - 1; i >= 0; i-- {
 		h = r.middlewares[i](h)
@@ -116,7 +183,8 @@ func (r *Router) chain(h server.Handler) server.Handler {
 //  1. Exact method and path match
 //  2. For HEAD requests, attempts to use GET handler with body removed
 //  3. Falls back to "ANY" method handler if available
-//  4. Returns 405 Method Not Allowed if path exists for other methods
+//  4. Returns 405 Method Not Allowed if path exists for other methods, with an Allow
+//     header listing them
 //  5. Returns 404 Not Found if no matching route exists
 //
 // Path parameters are extracted during route matching and added to the request
@@ -136,10 +204,10 @@ func (router *Router) Handler() server.Handler {
 				resp := response.NewBaseResponse()
 
 				if router.cors.optionPassthrough {
-					if handler, params := router.trees["OPTIONS"].Match(path); handler != nil {
+					if handler, params := router.match("OPTIONS", path); handler != nil {
 						r.PathParams = params
 						resp = handler(r)
-					} else if handler, params := router.trees["ANY"].Match(path); handler != nil {
+					} else if handler, params := router.anyTree.Match(path); handler != nil {
 						r.PathParams = params
 						resp = handler(r)
 					} else {
@@ -149,59 +217,51 @@ func (router *Router) Handler() server.Handler {
 					resp.WithStatusCode(response.StatusNoContent)
 				}
 
-				respHeaders := resp.GetHeaders()
-				for k, v := range maps.Collect(headers.All()) {
-					respHeaders.Set(k, v)
-				}
+				setHeaders(resp.GetHeaders(), headers)
 				return resp
 			}
 		}
 
-		handler, params := router.trees[reqMethod].Match(path)
+		handler, params := router.match(reqMethod, path)
 		if handler != nil {
 			r.PathParams = params
 			resp := handler(r)
 			if router.corsEnabled {
 				corsHeaders := router.cors.handleActualRequest(r)
-				resp.WithHeaders(maps.Collect(corsHeaders.All()))
+				resp.GetHeaders().AddAll(corsHeaders)
 			}
 			return resp
 		}
 
 		if reqMethod == "HEAD" {
-			getHandler, params := router.trees["GET"].Match(path)
+			getHandler, params := router.match("GET", path)
 			if getHandler != nil {
 				r.PathParams = params
 				resp := getHandler(r)
 				if router.corsEnabled {
 					corsHeaders := router.cors.handleActualRequest(r)
-					resp.WithHeaders(maps.Collect(corsHeaders.All()))
+					resp.GetHeaders().AddAll(corsHeaders)
 				}
 				return resp.WithBody(nil)
 			}
 		}
 
-		handler, params = router.trees["ANY"].Match(path)
+		handler, params = router.anyTree.Match(path)
 		if handler != nil {
 			r.PathParams = params
 			resp := handler(r)
 			if router.corsEnabled {
 				corsHeaders := router.cors.handleActualRequest(r)
-				resp.WithHeaders(maps.Collect(corsHeaders.All()))
+				resp.GetHeaders().AddAll(corsHeaders)
 			}
 			return resp
 		}
 
-		for method, tree := range router.trees {
-			if method == reqMethod || method == "ANY" {
-				continue
-			}
-			handler, _ := tree.Match(path)
-			if handler != nil {
-				return response.
-					NewTextResponse(response.GetStatusReason(response.StatusMethodNotAllowed)).
-					WithStatusCode(response.StatusMethodNotAllowed)
-			}
+		if allowed := router.allowedMethods(path); len(allowed) > 0 {
+			return response.
+				NewTextResponse(response.GetStatusReason(response.StatusMethodNotAllowed)).
+				WithStatusCode(response.StatusMethodNotAllowed).
+				WithHeader("Allow", strings.Join(allowed, ", "))
 		}
 
 		return router.notFoundHandler(r)
@@ -105,6 +105,74 @@ func TestRouter(t *testing.T) {
 	}
 }
 
+func TestRouter_CustomMethods(t *testing.T) {
+	router := NewRouter(nil)
+	router.Method("PROPFIND", "/dav/*path", func(r *request.Request) response.Response {
+		return response.NewTextResponse("propfind " + r.PathParams["path"]).WithStatusCode(207)
+	})
+	router.Method("MKCOL", "/dav/*path", func(r *request.Request) response.Response {
+		return response.NewTextResponse("mkcol").WithStatusCode(response.StatusCreated)
+	})
+	router.Get("/dav/*path", func(r *request.Request) response.Response {
+		return response.NewTextResponse("get")
+	})
+	handler := router.Handler()
+
+	testCases := []struct {
+		method         string
+		expectedStatus int
+		expectedBody   string
+		expectedAllow  string
+	}{
+		{"PROPFIND", 207, "propfind a/b", ""},
+		{"MKCOL", http.StatusCreated, "mkcol", ""},
+		{"propfind", http.StatusMethodNotAllowed, "Method Not Allowed", "GET, HEAD, MKCOL, PROPFIND"},
+		{"LOCK", http.StatusMethodNotAllowed, "Method Not Allowed", "GET, HEAD, MKCOL, PROPFIND"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method, func(t *testing.T) {
+			req, err := request.RequestFromReader(bytes.NewBufferString(tc.method+" /dav/a/b HTTP/1.1\r\nHost: x\r\n\r\n"), nil)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			require.NoError(t, handler(req).Write(w))
+
+			res, body, err := parseResponse(w)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, res.StatusCode)
+			assert.Equal(t, tc.expectedBody, body)
+			assert.Equal(t, tc.expectedAllow, res.Header.Get("Allow"))
+		})
+	}
+
+	assert.Panics(t, func() { router.Method("BAD METHOD", "/", nil) })
+}
+
+func TestRouter_MethodAny(t *testing.T) {
+	router := NewRouter(nil)
+	router.Method(AnyMethod, "/any", func(r *request.Request) response.Response {
+		return response.NewTextResponse("any " + r.Method)
+	})
+	handler := router.Handler()
+
+	for _, method := range []string{"GET", "DELETE", "PROPFIND"} {
+		req, err := request.RequestFromReader(bytes.NewBufferString(method+" /any HTTP/1.1\r\nHost: x\r\n\r\n"), nil)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		require.NoError(t, handler(req).Write(w))
+		res, body, err := parseResponse(w)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "any "+method, body)
+	}
+
+	// registered like Handle, not as a literal ANY method
+	assert.NotContains(t, router.trees, AnyMethod)
+	assert.Len(t, router.Routes(), 1)
+}
+
 func TestRouter_CustomNotFoundHandler(t *testing.T) {
 	router := NewRouter(nil)
 
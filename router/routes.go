@@ -0,0 +1,112 @@
+package router
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/shravanasati/shadowfax/server"
+)
+
+// AnyMethod is the method of the routes registered with [Router.Handle] in [Router.Routes].
+const AnyMethod = "ANY"
+
+// RouteInfo describes a registered route, see [Router.Routes].
+type RouteInfo struct {
+	// Method is the method of the route, [AnyMethod] for routes matching any method.
+	Method string
+	// Pattern is the normalized pattern of the route, eg. /users/:id.
+	Pattern string
+	// Params are the names of the path parameters, named wildcards included.
+	Params []string
+	// Middlewares is the number of router middlewares wrapping the handler.
+	Middlewares int
+	// Handler is the name of the handler function, eg. main.getUser.
+	Handler string
+}
+
+// Routes returns every registered route, sorted by pattern and method. A route registered with
+// [Router.Handle] is listed once with [AnyMethod], its handler is opaque: when it is the
+// handler of another router, the routes of that router aren't listed.
+func (r *Router) Routes() []RouteInfo {
+	var routes []RouteInfo
+	collect := func(method string, tree *TrieNode) {
+		tree.Walk(func(pattern string, handler server.Handler) {
+			name, ok := r.names[routeKey(method, pattern)]
+			if !ok {
+				name = funcName(handler)
+			}
+			routes = append(routes, RouteInfo{
+				Method:      method,
+				Pattern:     pattern,
+				Params:      patternParams(pattern),
+				Middlewares: len(r.middlewares),
+				Handler:     name,
+			})
+		})
+	}
+	for method, tree := range r.trees {
+		collect(method, tree)
+	}
+	collect(AnyMethod, r.anyTree)
+
+	slices.SortFunc(routes, func(a, b RouteInfo) int {
+		return cmp.Or(cmp.Compare(a.Pattern, b.Pattern), cmp.Compare(a.Method, b.Method))
+	})
+	return routes
+}
+
+// WriteRoutes writes the routes as an aligned table, one route per line, suitable for
+// diffing in code review.
+func (r *Router) WriteRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATTERN\tPARAMS\tMIDDLEWARES\tHANDLER")
+	for _, route := range r.Routes() {
+		params := "-"
+		if len(route.Params) > 0 {
+			params = strings.Join(route.Params, ",")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", route.Method, route.Pattern, params, route.Middlewares, route.Handler)
+	}
+	return tw.Flush()
+}
+
+// RoutesHandler returns a debug handler serving the route table of [Router.WriteRoutes]
+// as plain text. It lists the routes registered when the request is served.
+func (r *Router) RoutesHandler() server.Handler {
+	return func(req *request.Request) response.Response {
+		var sb strings.Builder
+		if err := r.WriteRoutes(&sb); err != nil {
+			return response.NewTextResponse(err.Error()).WithStatusCode(response.StatusInternalServerError)
+		}
+		return response.NewTextResponse(sb.String())
+	}
+}
+
+// patternParams returns the names of the path parameters of a pattern, unnamed wildcards
+// left out.
+func patternParams(pattern string) []string {
+	var params []string
+	for segment := range strings.SplitSeq(pattern, "/") {
+		if len(segment) > 1 && (segment[0] == ':' || segment[0] == '*') {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+// funcName returns the name of the function f, without the -fm suffix of method values.
+func funcName(f any) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	return strings.TrimSuffix(fn.Name(), "-fm")
+}
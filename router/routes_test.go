@@ -0,0 +1,69 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/shravanasati/shadowfax/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bookHandlers struct{}
+
+func (bookHandlers) list(r *request.Request) response.Response {
+	return response.NewTextResponse("books")
+}
+
+func newRoutesTestRouter() *Router {
+	router := NewRouter(nil)
+	router.Use(func(h server.Handler) server.Handler { return h }, func(h server.Handler) server.Handler { return h })
+
+	var books bookHandlers
+	router.Get("/books", books.list)
+	router.Post("/books/:id/", mockHandler)
+	router.Get("/books/:id", mockHandler)
+	router.Method("PROPFIND", "/dav/*path", mockHandler)
+	router.Handle("/static/*", mockHandler)
+	HandleTyped(router, "POST", "/shelves/:shelf/books", createBook)
+	return router
+}
+
+func TestRoutes(t *testing.T) {
+	router := newRoutesTestRouter()
+
+	const pkg = "github.com/shravanasati/shadowfax/router."
+	assert.Equal(t, []RouteInfo{
+		{Method: "GET", Pattern: "/books", Middlewares: 2, Handler: pkg + "bookHandlers.list"},
+		{Method: "GET", Pattern: "/books/:id", Params: []string{"id"}, Middlewares: 2, Handler: pkg + "mockHandler"},
+		{Method: "POST", Pattern: "/books/:id", Params: []string{"id"}, Middlewares: 2, Handler: pkg + "mockHandler"},
+		{Method: "PROPFIND", Pattern: "/dav/*path", Params: []string{"path"}, Middlewares: 2, Handler: pkg + "mockHandler"},
+		{Method: "POST", Pattern: "/shelves/:shelf/books", Params: []string{"shelf"}, Middlewares: 2, Handler: pkg + "createBook"},
+		{Method: AnyMethod, Pattern: "/static/*", Middlewares: 2, Handler: pkg + "mockHandler"},
+	}, router.Routes())
+
+	assert.Empty(t, NewRouter(nil).Routes())
+}
+
+func TestRoutesHandler(t *testing.T) {
+	router := newRoutesTestRouter()
+	router.Get("/debug/routes", router.RoutesHandler())
+
+	res, body := serveTyped(t, router.Handler(), "GET", "/debug/routes", "", "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var sb strings.Builder
+	require.NoError(t, router.WriteRoutes(&sb))
+	assert.Equal(t, sb.String(), body)
+
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	require.Len(t, lines, 8)
+	assert.Equal(t, strings.Fields("METHOD PATTERN PARAMS MIDDLEWARES HANDLER"), strings.Fields(lines[0]))
+	assert.Equal(t, strings.Fields("GET /books - 2 github.com/shravanasati/shadowfax/router.bookHandlers.list"), strings.Fields(lines[1]))
+	assert.Equal(t, strings.Fields("GET /debug/routes - 2 github.com/shravanasati/shadowfax/router.(*Router).RoutesHandler.func1"), strings.Fields(lines[5]))
+	assert.Equal(t, strings.Fields("ANY /static/* - 2 github.com/shravanasati/shadowfax/router.mockHandler"), strings.Fields(lines[7]))
+	assert.Equal(t, strings.Index(lines[0], "PATTERN"), strings.Index(lines[1], "/books"), "columns should be aligned")
+}
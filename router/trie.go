@@ -3,6 +3,8 @@
 package router
 
 import (
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/shravanasati/shadowfax/server"
@@ -22,6 +24,9 @@ type TrieNode struct {
 
 	// route handler to call
 	handler server.Handler
+
+	// pattern the route was registered with, eg. /users/:id
+	pattern string
 }
 
 func NewTrieNode() *TrieNode {
@@ -77,6 +82,35 @@ func (n *TrieNode) AddRoute(path string, handler server.Handler) {
 	}
 
 	currentNode.handler = handler
+	currentNode.pattern = normalizePattern(path)
+}
+
+// normalizePattern drops the query and empty segments of a route pattern.
+func normalizePattern(path string) string {
+	var segments []string
+	for segment := range strings.SplitSeq(strings.Trim(dropQuery(path), "/"), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// Walk calls fn for every route of the trie with the pattern it was registered with.
+// Static segments are visited in lexical order, before parameters and wildcards.
+func (n *TrieNode) Walk(fn func(pattern string, handler server.Handler)) {
+	if n.handler != nil {
+		fn(n.pattern, n.handler)
+	}
+	for _, segment := range slices.Sorted(maps.Keys(n.children)) {
+		n.children[segment].Walk(fn)
+	}
+	if n.paramChild != nil {
+		n.paramChild.Walk(fn)
+	}
+	if n.wildcardChild != nil {
+		n.wildcardChild.Walk(fn)
+	}
 }
 
 // Match finds a handler for a given path and extracts any parameters
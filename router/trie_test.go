@@ -88,3 +88,17 @@ func TestTrie_RootHandler(t *testing.T) {
 	handler, _ := trie.Match("/")
 	assert.NotNil(t, handler, "Expected a handler for the root path, but got nil")
 }
+
+func TestTrie_Walk(t *testing.T) {
+	trie := NewTrieNode()
+	for _, path := range []string{"/users/:id", "/static/*filepath", "/users/me/", "/", "/about?tab=1"} {
+		trie.AddRoute(path, server.Handler(mockHandler))
+	}
+
+	var patterns []string
+	trie.Walk(func(pattern string, handler server.Handler) {
+		assert.NotNil(t, handler)
+		patterns = append(patterns, pattern)
+	})
+	assert.Equal(t, []string{"/", "/about", "/static/*filepath", "/users/me", "/users/:id"}, patterns)
+}
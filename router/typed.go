@@ -0,0 +1,198 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/shravanasati/shadowfax/server"
+	"github.com/shravanasati/shadowfax/validate"
+)
+
+// TypedHandler handles a request with an input bound from the request, and returns the output
+// to encode in the response. See [HandleTyped].
+type TypedHandler[In, Out any] func(r *request.Request, in In) (Out, error)
+
+// RouteTypes are the input and output types of a route registered with [HandleTyped].
+type RouteTypes struct {
+	In, Out reflect.Type
+}
+
+// HandleTyped registers a typed handler for method and path. In must be a struct, it is filled
+// for every request:
+//   - from the body, decoded with [request.Request.DecodeJSON], unless it is a form. Fields
+//     with a binding tag are never set from the body, even when their parameter is absent,
+//   - then from path parameters, query parameters, headers, cookies and forms, based on the
+//     struct tags of [request.Request.DecodeParams],
+//   - and finally validated with [validate.Struct].
+//
+// Out is encoded with [response.Negotiate]. A nil pointer or interface is sent as 204 No
+// Content, while nil slices and maps are encoded empty, e.g. as [] and {} in JSON.
+//
+// Invalid input is rendered as a problem by the [server.ErrorRenderer] of the router: 415 for
+// bodies that aren't JSON, 413 for bodies too large, 400 for malformed JSON or parameters and 422
+// for validation failures, with an `errors` member listing them. Errors of the handler are
+// rendered the same way, see [server.ErrorRenderer.Render].
+//
+// The input and output types are recorded, see [Router.RouteTypes], and document the route along
+// with the options. It panics if In isn't a struct or method isn't a valid token.
+func HandleTyped[In, Out any](rt *Router, method, path string, h TypedHandler[In, Out], opts ...RouteOption) {
+	types := RouteTypes{In: reflect.TypeFor[In](), Out: reflect.TypeFor[Out]()}
+	if types.In.Kind() != reflect.Struct {
+		panic("router: typed handler input must be a struct, got " + types.In.String())
+	}
+
+	rt.method(method, path, func(r *request.Request) response.Response {
+		renderer := rt.errorRenderer()
+		in, err := bindTyped[In](r)
+		if err != nil {
+			return renderer.Render(r, err)
+		}
+
+		out, err := h(r, in)
+		if err != nil {
+			return renderer.Render(r, err)
+		}
+		rv := reflect.ValueOf(out)
+		if isNilOutput(rv) {
+			return response.NewBaseResponse().WithStatusCode(response.StatusNoContent)
+		}
+		resp, err := response.Negotiate(r, emptyOutput(rv))
+		if err != nil {
+			return renderer.Render(r, fmt.Errorf("encoding response: %w", err))
+		}
+		return resp
+	})
+	rt.types[routeKey(method, path)] = types
+	// the registered closure would be listed as HandleTyped[...].func1 by Routes
+	rt.names[routeKey(method, path)] = funcName(h)
+	rt.setMeta(method, path, nil, opts)
+}
+
+// RouteTypes returns the input and output types of the route registered with [HandleTyped] for
+// method and path.
+func (r *Router) RouteTypes(method, path string) (RouteTypes, bool) {
+	types, ok := r.types[routeKey(method, path)]
+	return types, ok
+}
+
+func (r *Router) errorRenderer() *server.ErrorRenderer {
+	if r.renderer != nil {
+		return r.renderer
+	}
+	return server.DefaultErrorRenderer
+}
+
+func isNilOutput(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// emptyOutput returns the output v, with nil slices and maps replaced by empty ones so that
+// an empty list isn't encoded as null.
+func emptyOutput(v reflect.Value) any {
+	switch {
+	case v.Kind() == reflect.Slice && v.IsNil():
+		return reflect.MakeSlice(v.Type(), 0, 0).Interface()
+	case v.Kind() == reflect.Map && v.IsNil():
+		return reflect.MakeMap(v.Type()).Interface()
+	}
+	return v.Interface()
+}
+
+// bindTyped fills an In from the request and validates it. Errors are HTTP errors, apart from
+// misconfigured validation rules.
+func bindTyped[In any](r *request.Request) (In, error) {
+	var in In
+
+	if r.ContentLength() > 0 || r.Headers.Get("transfer-encoding") != "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Headers.Get("content-type"))
+		if mediaType != "application/x-www-form-urlencoded" && mediaType != "multipart/form-data" {
+			if err := r.DecodeJSON(&in, nil); err != nil {
+				return in, bindHTTPError(err)
+			}
+			// a body mustn't fill fields meant for headers set by a proxy or middleware
+			clearBindFields(reflect.ValueOf(&in).Elem())
+		}
+	}
+
+	if err := r.DecodeParams(&in); err != nil {
+		return in, bindHTTPError(err)
+	}
+
+	if err := validate.Struct(in); err != nil {
+		var errs validate.Errors
+		if !errors.As(err, &errs) {
+			return in, err
+		}
+		return in, response.NewHTTPError(response.StatusUnprocessableEntity, "the request failed validation").
+			WithExtension("errors", errs).
+			WithCause(err)
+	}
+	return in, nil
+}
+
+// clearBindFields zeroes the fields of struct v filled by [request.Request.DecodeParams],
+// walking untagged structs the way it does.
+func clearBindFields(v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		if hasBindingTag(f, "form") {
+			fv.SetZero()
+			continue
+		}
+		if fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType && !reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType) {
+			clearBindFields(fv)
+		}
+	}
+}
+
+// paramError is a parameter that couldn't be bound, in the `errors` member of problems.
+type paramError struct {
+	Field  string `json:"field"`
+	Source string `json:"source"`
+	Detail string `json:"detail"`
+}
+
+// bindHTTPError converts an error reading the input into an HTTP error.
+func bindHTTPError(err error) error {
+	var jsonErr *request.JSONError
+	var bindErr *request.BindError
+	switch {
+	case errors.Is(err, request.ErrUnsupportedJSONType), errors.Is(err, request.ErrUnsupportedFormType):
+		return response.NewHTTPError(response.StatusUnsupportedMediaType, "the request body must be JSON or a form").WithCause(err)
+	case errors.Is(err, request.ErrBodyTooLarge):
+		return response.NewHTTPError(response.StatusPayloadTooLarge, "the request body is too large").WithCause(err)
+	case errors.As(err, &jsonErr):
+		return response.NewHTTPError(response.StatusBadRequest, jsonErr.Detail).
+			WithExtension("path", jsonErr.Path).
+			WithCause(err)
+	case errors.As(err, &bindErr):
+		params := make([]paramError, len(bindErr.Errors))
+		for i, fe := range bindErr.Errors {
+			params[i] = paramError{Field: fe.Name, Source: fe.Source, Detail: fe.Err.Error()}
+		}
+		return response.NewHTTPError(response.StatusBadRequest, "invalid request parameters").
+			WithExtension("errors", params).
+			WithCause(err)
+	case errors.Is(err, request.ErrMalformedForm), errors.Is(err, request.ErrMalformedMultipart):
+		return response.NewHTTPError(response.StatusBadRequest, "the request body is a malformed form").WithCause(err)
+	}
+	return err
+}
@@ -0,0 +1,197 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/shravanasati/shadowfax/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type createBookInput struct {
+	ShelfID int    `path:"shelf" json:"-"`
+	DryRun  bool   `query:"dry_run" json:"-"`
+	Title   string `json:"title" validate:"required,max=20"`
+	Pages   int    `json:"pages" validate:"min=1"`
+}
+
+type book struct {
+	Shelf int    `json:"shelf"`
+	Title string `json:"title"`
+	Pages int    `json:"pages"`
+}
+
+var errShelfFull = response.NewHTTPError(response.StatusConflict, "the shelf is full")
+
+func createBook(r *request.Request, in createBookInput) (*book, error) {
+	switch {
+	case in.ShelfID == 13:
+		return nil, errShelfFull
+	case in.ShelfID == 500:
+		return nil, errors.New("disk on fire")
+	case in.DryRun:
+		return nil, nil
+	}
+	return &book{Shelf: in.ShelfID, Title: in.Title, Pages: in.Pages}, nil
+}
+
+func serveTyped(t *testing.T, handler server.Handler, method, target, contentType, body string) (*http.Response, string) {
+	t.Helper()
+	httpReq := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, httpReq.Write(&buf))
+	req, err := request.RequestFromReader(&buf, nil)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, handler(req).Write(w))
+	res, respBody, err := parseResponse(w)
+	require.NoError(t, err)
+	return res, respBody
+}
+
+func TestHandleTyped(t *testing.T) {
+	router := NewRouter(&RouterOptions{ErrorRenderer: &server.ErrorRenderer{
+		Log: func(*request.Request, error, response.StatusCode) {},
+	}})
+	HandleTyped(router, "POST", "/shelves/:shelf/books", createBook)
+	handler := router.Handler()
+
+	res, body := serveTyped(t, handler, "POST", "/shelves/7/books", "application/json", `{"title": "The Hobbit", "pages": 310}`)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"shelf": 7, "title": "The Hobbit", "pages": 310}`, body)
+
+	res, _ = serveTyped(t, handler, "POST", "/shelves/7/books?dry_run=true", "application/json", `{"title": "The Hobbit", "pages": 310}`)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	// nil slices and maps are empty lists and objects, not no content
+	HandleTyped(router, "GET", "/shelves/:shelf/books", func(r *request.Request, in struct{}) ([]book, error) { return nil, nil })
+	HandleTyped(router, "GET", "/shelves/:shelf/counts", func(r *request.Request, in struct{}) (map[string]int, error) { return nil, nil })
+	res, body = serveTyped(t, handler, "GET", "/shelves/7/books", "", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "[]", body)
+	res, body = serveTyped(t, handler, "GET", "/shelves/7/counts", "", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "{}", body)
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		status      int
+		problem     string
+	}{
+		{"validation", "/shelves/7/books", "application/json", `{"pages": 0}`, http.StatusUnprocessableEntity,
+			`{"title": "Unprocessable Entity", "status": 422, "detail": "the request failed validation", "errors": [
+				{"field": "title", "rule": "required", "message": "is required"},
+				{"field": "pages", "rule": "min", "param": "1", "message": "must be at least 1"}]}`},
+		{"malformed json", "/shelves/7/books", "application/json", `{"title": 42}`, http.StatusBadRequest,
+			`{"title": "Bad Request", "status": 400, "detail": "expected string, got number", "path": "$.title"}`},
+		{"invalid parameter", "/shelves/seven/books?dry_run=maybe", "application/json", `{"title": "a", "pages": 1}`, http.StatusBadRequest,
+			`{"title": "Bad Request", "status": 400, "detail": "invalid request parameters", "errors": [
+				{"field": "shelf", "source": "path", "detail": "invalid parameter: \"seven\" is not a valid int"},
+				{"field": "dry_run", "source": "query", "detail": "invalid parameter: \"maybe\" is not a valid bool"}]}`},
+		{"unsupported body", "/shelves/7/books", "text/plain", `title`, http.StatusUnsupportedMediaType,
+			`{"title": "Unsupported Media Type", "status": 415, "detail": "the request body must be JSON or a form"}`},
+		{"handler http error", "/shelves/13/books", "application/json", `{"title": "a", "pages": 1}`, http.StatusConflict,
+			`{"title": "Conflict", "status": 409, "detail": "the shelf is full"}`},
+		{"handler error", "/shelves/500/books", "application/json", `{"title": "a", "pages": 1}`, http.StatusInternalServerError,
+			`{"title": "Internal Server Error", "status": 500}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := serveTyped(t, handler, "POST", tt.target, tt.contentType, tt.body)
+			assert.Equal(t, tt.status, res.StatusCode)
+			assert.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+			assert.JSONEq(t, tt.problem, body)
+		})
+	}
+}
+
+func TestHandleTypedQueryAndNegotiation(t *testing.T) {
+	type searchInput struct {
+		Query string `query:"q" validate:"required"`
+		Limit int    `query:"limit" validate:"omitempty,max=2"`
+	}
+	router := NewRouter(nil)
+	HandleTyped(router, "GET", "/books", func(r *request.Request, in searchInput) ([]book, error) {
+		books := []book{{Title: in.Query + " 1"}, {Title: in.Query + " 2"}, {Title: in.Query + " 3"}}
+		if in.Limit > 0 {
+			books = books[:in.Limit]
+		}
+		return books, nil
+	})
+	handler := router.Handler()
+
+	httpReq := httptest.NewRequest("GET", "/books?q=ring&limit=2", nil)
+	httpReq.Header.Set("Accept", "text/csv")
+	var buf bytes.Buffer
+	require.NoError(t, httpReq.Write(&buf))
+	req, err := request.RequestFromReader(&buf, nil)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	require.NoError(t, handler(req).Write(w))
+	res, body, err := parseResponse(w)
+	require.NoError(t, err)
+	assert.Equal(t, "text/csv; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.Equal(t, "Shelf,Title,Pages\n0,ring 1,0\n0,ring 2,0\n", body)
+
+	res, body = serveTyped(t, handler, "GET", "/books?limit=3", "", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	var problem struct {
+		Errors []struct{ Field string } `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &problem))
+	assert.Equal(t, "q", problem.Errors[0].Field)
+	assert.Equal(t, "limit", problem.Errors[1].Field)
+
+	types, ok := router.RouteTypes("GET", "/books")
+	require.True(t, ok)
+	assert.Equal(t, reflect.TypeFor[searchInput](), types.In)
+	assert.Equal(t, reflect.TypeFor[[]book](), types.Out)
+	_, ok = router.RouteTypes("POST", "/books")
+	assert.False(t, ok)
+}
+
+func TestHandleTypedRejectsNonStructInput(t *testing.T) {
+	assert.Panics(t, func() {
+		HandleTyped(NewRouter(nil), "GET", "/", func(r *request.Request, in string) (string, error) { return in, nil })
+	})
+}
+
+func TestHandleTypedIgnoresBindFieldsInBody(t *testing.T) {
+	type authInfo struct {
+		Role string `header:"X-Role"`
+	}
+	type commentInput struct {
+		UserID int    `header:"X-User-ID"`
+		PostID string `path:"post"`
+		Auth   authInfo
+		Owner  *authInfo
+		Text   string `json:"text"`
+	}
+	router := NewRouter(nil)
+	HandleTyped(router, "POST", "/comments", func(r *request.Request, in commentInput) (commentInput, error) {
+		return in, nil
+	})
+
+	body := `{"UserID": 1, "PostID": "p1", "Auth": {"Role": "admin"}, "Owner": {"Role": "admin"}, "text": "hi"}`
+	res, respBody := serveTyped(t, router.Handler(), "POST", "/comments", "application/json", body)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"UserID": 0, "PostID": "", "Auth": {"Role": ""}, "Owner": {"Role": ""}, "text": "hi"}`, respBody)
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// aLongTimeAgo is a non-zero time in the past, used to unblock pending reads.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// connReader is the reader requests are parsed from.
+// While a handler runs, it keeps a single byte read pending on the connection so that
+// a client disconnect (or any other read error) cancels the connection context.
+// The byte, if one arrives, is handed out by the next call to Read.
+type connReader struct {
+	conn   net.Conn
+	cancel context.CancelFunc
+
+	mu           sync.Mutex
+	readDeadline time.Time
+	inRead       bool
+	aborted      bool
+	hasByte      bool
+	byteBuf      [1]byte
+	done         chan struct{}
+}
+
+func newConnReader(conn net.Conn, cancel context.CancelFunc) *connReader {
+	return &connReader{conn: conn, cancel: cancel}
+}
+
+// setReadDeadline sets the read deadline on the connection and remembers it,
+// so that it can be restored after a background read is aborted.
+func (cr *connReader) setReadDeadline(t time.Time) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.readDeadline = t
+	cr.conn.SetReadDeadline(t)
+}
+
+// startBackgroundRead starts watching the connection for a disconnect.
+func (cr *connReader) startBackgroundRead() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.inRead || cr.hasByte {
+		return
+	}
+	cr.inRead = true
+	cr.aborted = false
+	cr.done = make(chan struct{})
+	go cr.backgroundRead()
+}
+
+func (cr *connReader) backgroundRead() {
+	n, err := cr.conn.Read(cr.byteBuf[:])
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if n == 1 {
+		cr.hasByte = true
+	}
+	if err != nil {
+		var ne net.Error
+		if !(cr.aborted && errors.As(err, &ne) && ne.Timeout()) {
+			// the client went away or the read failed
+			cr.cancel()
+		}
+	}
+	cr.inRead = false
+	close(cr.done)
+}
+
+// abortPendingRead stops the background read, if any, and waits for it to return.
+func (cr *connReader) abortPendingRead() {
+	cr.mu.Lock()
+	if !cr.inRead {
+		cr.mu.Unlock()
+		return
+	}
+	cr.aborted = true
+	done := cr.done
+	cr.conn.SetReadDeadline(aLongTimeAgo)
+	cr.mu.Unlock()
+
+	<-done
+
+	cr.mu.Lock()
+	cr.conn.SetReadDeadline(cr.readDeadline)
+	cr.mu.Unlock()
+}
+
+// Read implements the io.Reader interface.
+func (cr *connReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	cr.abortPendingRead()
+
+	cr.mu.Lock()
+	if cr.hasByte {
+		p[0] = cr.byteBuf[0]
+		cr.hasByte = false
+		cr.mu.Unlock()
+		return 1, nil
+	}
+	cr.mu.Unlock()
+
+	n, err := cr.conn.Read(p)
+	if err != nil {
+		cr.cancel()
+	}
+	return n, err
+}
+
+// connState is the lifecycle state of a client connection, used by [Server.Shutdown].
+type connState int
+
+const (
+	// waiting for the next request
+	stateIdle connState = iota
+	// reading a request, running the handler or writing the response
+	stateActive
+	// closed by the server
+	stateClosed
+)
+
+// trackedConn is a client connection registered with the server.
+type trackedConn struct {
+	net.Conn
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	state  connState
+	killed bool
+}
+
+// setState moves the connection to the given state.
+// It returns false if the connection has already been closed by the server.
+func (tc *trackedConn) setState(state connState) bool {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.state == stateClosed {
+		return false
+	}
+	tc.state = state
+	return true
+}
+
+// closeIfIdle closes the connection if it's waiting for the next request.
+func (tc *trackedConn) closeIfIdle() bool {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.state != stateIdle {
+		return false
+	}
+	tc.state = stateClosed
+	tc.Conn.Close()
+	return true
+}
+
+// kill force-closes the connection and cancels its context.
+func (tc *trackedConn) kill() {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.killed = true
+	if tc.state != stateClosed {
+		tc.state = stateClosed
+		tc.Conn.Close()
+	}
+	tc.cancel()
+}
+
+// Close closes the connection unless the server already did.
+func (tc *trackedConn) Close() error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.state == stateClosed {
+		return nil
+	}
+	tc.state = stateClosed
+	return tc.Conn.Close()
+}
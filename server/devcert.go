@@ -0,0 +1,197 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	devCAName       = "shadowfax-dev-ca"
+	devLeafName     = "shadowfax-dev"
+	devCAValidity   = 10 * 365 * 24 * time.Hour
+	devLeafValidity = 90 * 24 * time.Hour
+
+	// certificates expiring sooner than this are regenerated
+	devRenewBefore = 24 * time.Hour
+)
+
+// defaultDevHosts are always covered by development certificates.
+var defaultDevHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// DevCertificates are the files of a development certificate authority and of a leaf
+// certificate issued by it.
+type DevCertificates struct {
+	// CertificateFiles is the leaf certificate and key to serve, see [TLSOptions].
+	CertificateFiles
+
+	// CACertFile is the certificate of the development CA. Add it to the trust store of
+	// your browser or pass it to curl with --cacert to avoid certificate warnings.
+	CACertFile string
+}
+
+// EnsureDevCertificates creates a self-signed development CA and a certificate issued by it
+// for localhost, 127.0.0.1, ::1 and the given hosts (names or IP addresses), and stores them
+// in dir. The files are reused across calls and only regenerated when missing, close to
+// expiry, or not covering all the hosts.
+//
+// The certificates are meant for local development only, never use them in production.
+func EnsureDevCertificates(dir string, hosts ...string) (*DevCertificates, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	certs := &DevCertificates{
+		CertificateFiles: CertificateFiles{
+			CertFile: filepath.Join(dir, devLeafName+".crt"),
+			KeyFile:  filepath.Join(dir, devLeafName+".key"),
+		},
+		CACertFile: filepath.Join(dir, devCAName+".crt"),
+	}
+	caKeyFile := filepath.Join(dir, devCAName+".key")
+
+	caCert, caKey, err := loadDevCert(certs.CACertFile, caKeyFile)
+	if err != nil || !devCertValid(caCert, nil) {
+		caCert, caKey, err = createDevCA(certs.CACertFile, caKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create development CA: %w", err)
+		}
+	}
+
+	hosts = append(slices.Clone(defaultDevHosts), hosts...)
+	leaf, _, err := loadDevCert(certs.CertFile, certs.KeyFile)
+	if err != nil || !devCertValid(leaf, hosts) || leaf.CheckSignatureFrom(caCert) != nil {
+		if err := createDevLeaf(certs.CertificateFiles, caCert, caKey, hosts); err != nil {
+			return nil, fmt.Errorf("unable to create development certificate: %w", err)
+		}
+	}
+
+	return certs, nil
+}
+
+// devCertValid reports whether the certificate is valid for a while and covers all the hosts.
+func devCertValid(cert *x509.Certificate, hosts []string) bool {
+	if time.Now().Add(devRenewBefore).After(cert.NotAfter) {
+		return false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func loadDevCert(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("unsupported private key type")
+	}
+	return pair.Leaf, signer, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func createDevCA(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Shadowfax Development CA", Organization: []string{"Shadowfax"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(devCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeDevCert(certFile, keyFile, der, key); err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func createDevLeaf(files CertificateFiles, caCert *x509.Certificate, caKey crypto.Signer, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hosts[0], Organization: []string{"Shadowfax Development"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(devLeafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	return writeDevCert(files.CertFile, files.KeyFile, der, key)
+}
+
+func writeDevCert(certFile, keyFile string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	var certPEM, keyPEM bytes.Buffer
+	pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	pem.Encode(&keyPEM, &pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+
+	if err := os.WriteFile(keyFile, keyPEM.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, certPEM.Bytes(), 0o644)
+}
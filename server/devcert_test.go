@@ -0,0 +1,76 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"testing"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestCert(t *testing.T, file string) *x509.Certificate {
+	t.Helper()
+	pair, err := tls.LoadX509KeyPair(file, file[:len(file)-len(".crt")]+".key")
+	require.NoError(t, err)
+	return pair.Leaf
+}
+
+func TestEnsureDevCertificates(t *testing.T) {
+	dir := t.TempDir()
+
+	certs, err := EnsureDevCertificates(dir, "app.test", "10.0.0.1")
+	require.NoError(t, err)
+
+	ca := loadTestCert(t, certs.CACertFile)
+	assert.True(t, ca.IsCA)
+
+	leaf := loadTestCert(t, certs.CertFile)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	for _, host := range []string{"localhost", "127.0.0.1", "::1", "app.test", "10.0.0.1"} {
+		_, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+		assert.NoError(t, err, host)
+	}
+
+	info, err := os.Stat(certs.KeyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// cached certificates are reused
+	again, err := EnsureDevCertificates(dir, "app.test")
+	require.NoError(t, err)
+	assert.Equal(t, leaf.SerialNumber, loadTestCert(t, again.CertFile).SerialNumber)
+
+	// a new host issues a new leaf from the same CA
+	again, err = EnsureDevCertificates(dir, "other.test")
+	require.NoError(t, err)
+	newLeaf := loadTestCert(t, again.CertFile)
+	assert.NotEqual(t, leaf.SerialNumber, newLeaf.SerialNumber)
+	assert.NoError(t, newLeaf.VerifyHostname("other.test"))
+	assert.Equal(t, ca.SerialNumber, loadTestCert(t, again.CACertFile).SerialNumber)
+	assert.NoError(t, newLeaf.CheckSignatureFrom(ca))
+}
+
+func TestServerWithDevCertificates(t *testing.T) {
+	certs, err := EnsureDevCertificates(t.TempDir())
+	require.NoError(t, err)
+
+	_, addr := startTestServer(t, ServerOpts{
+		TLS: &TLSOptions{Certificates: []CertificateFiles{certs.CertificateFiles}},
+	}, func(r *request.Request) response.Response {
+		return response.NewTextResponse("dev")
+	})
+
+	caPEM, err := os.ReadFile(certs.CACertFile)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(caPEM))
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: "localhost", RootCAs: roots})
+	require.NoError(t, err)
+	conn.Close()
+}
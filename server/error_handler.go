@@ -0,0 +1,148 @@
+package server
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"log"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+)
+
+// ErrorHandler is a handler that can fail. Adapt it into a [Handler] with [HandleErrors] or
+// [ErrorRenderer.Handler].
+type ErrorHandler func(*request.Request) (response.Response, error)
+
+// ErrorRenderer turns errors returned by an [ErrorHandler] into responses. Errors wrapping a
+// [*response.HTTPError] are rendered with its status and members, other errors are masked.
+// Problems are sent as application/problem+json, or as HTML to clients preferring text/html.
+// The zero value is ready to use.
+type ErrorRenderer struct {
+	// Log is called with every error and the status it's rendered with. By default, errors
+	// rendered with a 5xx status are logged with the standard logger.
+	Log func(r *request.Request, err error, status response.StatusCode)
+
+	// Mask converts errors that don't wrap a [*response.HTTPError] into the problem sent to the
+	// client, hiding internal details. By default, they become a 500 Internal Server Error
+	// without detail.
+	Mask func(r *request.Request, err error) *response.HTTPError
+
+	// HTML writes the HTML page of a problem. By default, a minimal page with the status,
+	// title and detail is written.
+	HTML func(w io.Writer, e *response.HTTPError) error
+}
+
+var problemPage = template.Must(template.New("problem").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Status}} {{.StatusTitle}}</title></head>
+<body>
+<h1>{{.Status}} {{.StatusTitle}}</h1>
+{{with .Detail}}<p>{{.}}</p>
+{{end}}</body>
+</html>
+`))
+
+func defaultProblemHTML(w io.Writer, e *response.HTTPError) error {
+	return problemPage.Execute(w, e)
+}
+
+func defaultErrorLog(r *request.Request, err error, status response.StatusCode) {
+	if status >= 500 {
+		log.Printf("%s %s: %v", r.RequestLine.Method, r.RequestLine.Target, err)
+	}
+}
+
+func defaultErrorMask(r *request.Request, err error) *response.HTTPError {
+	return &response.HTTPError{Status: response.StatusInternalServerError, Err: err}
+}
+
+// renderedProblem is a problem along with the HTML writer of its renderer.
+type renderedProblem struct {
+	err  *response.HTTPError
+	html func(w io.Writer, e *response.HTTPError) error
+}
+
+// problemNegotiator picks the format of problems, it is shared by every renderer.
+var problemNegotiator = newProblemNegotiator()
+
+func newProblemNegotiator() *response.Negotiator {
+	n := &response.Negotiator{}
+	n.Register("application/problem+json", func(w io.Writer, v any) error {
+		return response.EncodeJSON(w, v.(renderedProblem).err)
+	})
+	n.Register("text/html; charset=utf-8", func(w io.Writer, v any) error {
+		p := v.(renderedProblem)
+		return p.html(w, p.err)
+	})
+	return n
+}
+
+// Render converts err into a response, see [ErrorRenderer]. Problems without an error status
+// are sent as 500 Internal Server Error, see [response.HTTPError.ResponseStatus].
+func (er *ErrorRenderer) Render(r *request.Request, err error) response.Response {
+	var httpErr *response.HTTPError
+	if !errors.As(err, &httpErr) {
+		mask := er.Mask
+		if mask == nil {
+			mask = defaultErrorMask
+		}
+		httpErr = mask(r, err)
+		if httpErr == nil {
+			httpErr = defaultErrorMask(r, err)
+		}
+	}
+	if status := httpErr.ResponseStatus(); status != httpErr.Status {
+		fixed := *httpErr
+		fixed.Status = status
+		httpErr = &fixed
+	}
+
+	logErr := er.Log
+	if logErr == nil {
+		logErr = defaultErrorLog
+	}
+	logErr(r, err, httpErr.Status)
+
+	html := er.HTML
+	if html == nil {
+		html = defaultProblemHTML
+	}
+	resp, negotiateErr := problemNegotiator.Negotiate(r, renderedProblem{httpErr, html})
+	if negotiateErr == nil && resp.GetStatusCode() != response.StatusNotAcceptable {
+		return resp.WithStatusCode(httpErr.Status)
+	}
+
+	// clients accepting neither format still get the problem
+	resp, negotiateErr = response.NewProblemResponse(httpErr)
+	if negotiateErr != nil {
+		log.Println("rendering error response:", negotiateErr)
+		return response.
+			NewTextResponse(response.GetStatusReason(httpErr.Status)).
+			WithStatusCode(httpErr.Status)
+	}
+	return resp.WithHeader("vary", "Accept")
+}
+
+// Handler adapts h into a [Handler]. Errors returned by h are rendered with [ErrorRenderer.Render],
+// and a nil response with a nil error is sent as 204 No Content.
+func (er *ErrorRenderer) Handler(h ErrorHandler) Handler {
+	return func(r *request.Request) response.Response {
+		resp, err := h(r)
+		if err != nil {
+			return er.Render(r, err)
+		}
+		if resp == nil {
+			return response.NewBaseResponse().WithStatusCode(response.StatusNoContent)
+		}
+		return resp
+	}
+}
+
+// DefaultErrorRenderer is the renderer used by [HandleErrors].
+var DefaultErrorRenderer = &ErrorRenderer{}
+
+// HandleErrors adapts h into a [Handler] rendering its errors with the [DefaultErrorRenderer].
+func HandleErrors(h ErrorHandler) Handler {
+	return DefaultErrorRenderer.Handler(h)
+}
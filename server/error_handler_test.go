@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAcceptRequest(t *testing.T, accept ...string) *request.Request {
+	t.Helper()
+	raw := "GET /books/7 HTTP/1.1\r\nHost: x\r\n"
+	for _, a := range accept {
+		raw += "Accept: " + a + "\r\n"
+	}
+	return parseRequest(t, raw+"\r\n")
+}
+
+var errDatabase = errors.New("connection refused by 10.0.0.3")
+
+func findBook(r *request.Request) (response.Response, error) {
+	switch r.Headers.Get("x-case") {
+	case "missing":
+		return nil, response.NewHTTPError(response.StatusNotFound, "no book with id 7").
+			WithExtension("id", 7)
+	case "wrapped":
+		err := response.NewHTTPError(response.StatusConflict, "out of stock").WithCause(errDatabase)
+		return nil, fmt.Errorf("reserving: %w", err)
+	case "internal":
+		return nil, fmt.Errorf("loading book: %w", errDatabase)
+	case "empty":
+		return nil, nil
+	}
+	return response.NewTextResponse("The Hobbit"), nil
+}
+
+func TestHandleErrors(t *testing.T) {
+	var logged []string
+	renderer := &ErrorRenderer{
+		Log: func(r *request.Request, err error, status response.StatusCode) {
+			logged = append(logged, fmt.Sprintf("%d %v", status, err))
+		},
+	}
+	handler := renderer.Handler(findBook)
+
+	tests := []struct {
+		name    string
+		problem map[string]any
+	}{
+		{"missing", map[string]any{"title": "Not Found", "status": float64(404), "detail": "no book with id 7", "id": float64(7)}},
+		{"wrapped", map[string]any{"title": "Conflict", "status": float64(409), "detail": "out of stock"}},
+		{"internal", map[string]any{"title": "Internal Server Error", "status": float64(500)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newAcceptRequest(t)
+			r.Headers.Set("X-Case", tt.name)
+			resp := handler(r)
+
+			assert.Equal(t, response.StatusCode(tt.problem["status"].(float64)), resp.GetStatusCode())
+			assert.Equal(t, "application/problem+json", resp.GetHeaders().Get("content-type"))
+			assert.Equal(t, "Accept", resp.GetHeaders().Get("vary"))
+			var problem map[string]any
+			require.NoError(t, json.Unmarshal([]byte(readBody(t, resp)), &problem))
+			assert.Equal(t, tt.problem, problem)
+		})
+	}
+	assert.Equal(t, []string{
+		"404 404 Not Found: no book with id 7",
+		"409 reserving: 409 Conflict: out of stock: connection refused by 10.0.0.3",
+		"500 loading book: connection refused by 10.0.0.3",
+	}, logged)
+
+	r := newAcceptRequest(t)
+	assert.Equal(t, "The Hobbit", readBody(t, handler(r)))
+	r.Headers.Set("X-Case", "empty")
+	assert.Equal(t, response.StatusNoContent, handler(r).GetStatusCode())
+}
+
+func TestErrorRendererHTML(t *testing.T) {
+	err := response.NewHTTPError(response.StatusNotFound, "no book <b>7</b>")
+
+	resp := HandleErrors(func(*request.Request) (response.Response, error) { return nil, err })(
+		newAcceptRequest(t, "text/html,application/xhtml+xml,*/*;q=0.8"))
+	assert.Equal(t, response.StatusNotFound, resp.GetStatusCode())
+	assert.Equal(t, "text/html; charset=utf-8", resp.GetHeaders().Get("content-type"))
+	body := readBody(t, resp)
+	assert.Contains(t, body, "<h1>404 Not Found</h1>")
+	assert.Contains(t, body, "<p>no book &lt;b&gt;7&lt;/b&gt;</p>")
+
+	// clients accepting neither format get problem+json
+	resp = (&ErrorRenderer{}).Render(newAcceptRequest(t, "image/png"), err)
+	assert.Equal(t, response.StatusNotFound, resp.GetStatusCode())
+	assert.Equal(t, "application/problem+json", resp.GetHeaders().Get("content-type"))
+}
+
+func TestErrorRendererHooks(t *testing.T) {
+	renderer := &ErrorRenderer{
+		Log: func(*request.Request, error, response.StatusCode) {},
+		Mask: func(r *request.Request, err error) *response.HTTPError {
+			if errors.Is(err, errDatabase) {
+				return &response.HTTPError{Status: response.StatusServiceUnavailable, Type: "https://example.com/probs/db", Instance: r.RequestLine.Target}
+			}
+			return nil
+		},
+		HTML: func(w io.Writer, e *response.HTTPError) error {
+			_, err := fmt.Fprintf(w, "<p>%d</p>", e.Status)
+			return err
+		},
+	}
+
+	resp := renderer.Render(newAcceptRequest(t), errDatabase)
+	assert.Equal(t, response.StatusServiceUnavailable, resp.GetStatusCode())
+	assert.JSONEq(t, `{"type": "https://example.com/probs/db", "title": "Service Unavailable", "status": 503, "instance": "/books/7"}`, readBody(t, resp))
+
+	// a nil mask result falls back to a 500
+	resp = renderer.Render(newAcceptRequest(t, "text/html"), errors.New("other"))
+	assert.Equal(t, response.StatusInternalServerError, resp.GetStatusCode())
+	assert.Equal(t, "<p>500</p>", readBody(t, resp))
+}
+
+func TestErrorRendererStatus(t *testing.T) {
+	renderer := &ErrorRenderer{Log: func(*request.Request, error, response.StatusCode) {}}
+
+	for _, err := range []*response.HTTPError{{Detail: "no status"}, {Status: response.StatusOK, Detail: "not an error"}} {
+		resp := renderer.Render(newAcceptRequest(t), err)
+		assert.Equal(t, response.StatusInternalServerError, resp.GetStatusCode())
+		assert.JSONEq(t, `{"title": "Internal Server Error", "status": 500, "detail": "`+err.Detail+`"}`, readBody(t, resp))
+
+		resp = renderer.Render(newAcceptRequest(t, "text/html"), err)
+		assert.Equal(t, response.StatusInternalServerError, resp.GetStatusCode())
+		assert.Contains(t, readBody(t, resp), "<h1>500 Internal Server Error</h1>")
+	}
+}
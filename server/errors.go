@@ -0,0 +1,15 @@
+package server
+
+import "errors"
+
+// ErrServerClosed is returned by [Server.ListenAndServe] and [Server.ServeListener] after the server is closed.
+var ErrServerClosed = errors.New("server closed")
+
+// ErrAlreadyServing is returned by [Server.ServeListener] when the server is already serving on a listener.
+var ErrAlreadyServing = errors.New("server is already serving")
+
+// ErrFinalResponseWritten is returned by [SendInformational] once the handler has returned its final response.
+var ErrFinalResponseWritten = errors.New("final response already written")
+
+// ErrNotServed is returned by [SendInformational] for requests that aren't being served by a [Server].
+var ErrNotServed = errors.New("request is not being served")
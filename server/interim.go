@@ -0,0 +1,94 @@
+package server
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/shravanasati/shadowfax/headers"
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+)
+
+// interimWriter writes the informational responses of a request, until the final response.
+type interimWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	done bool
+}
+
+func (iw *interimWriter) write(code response.StatusCode, h *headers.Headers) error {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+	if iw.done {
+		return ErrFinalResponseWritten
+	}
+	return response.NewResponseWriter(iw.w).WriteInformational(code, h)
+}
+
+// finish prevents informational responses from being written after the final response.
+func (iw *interimWriter) finish() {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+	iw.done = true
+}
+
+var interimWriterKey = request.NewKey[*interimWriter]("interim-writer")
+
+// SendInformational sends an informational (1xx) response with the given headers to the
+// client, ahead of the final response returned by the handler. It can be called any number
+// of times while the handler runs.
+//
+// HTTP/1.0 clients don't understand informational responses, nothing is sent to them and
+// nil is returned. 101 Switching Protocols isn't supported.
+func SendInformational(r *request.Request, code response.StatusCode, h *headers.Headers) error {
+	iw, ok := request.GetValue(r, interimWriterKey)
+	if !ok {
+		return ErrNotServed
+	}
+	if r.IsHTTP10() {
+		return nil
+	}
+	return iw.write(code, h)
+}
+
+// SendEarlyHints sends a 103 Early Hints response with the given Link header values, so
+// browsers can start preloading resources while the final response is being prepared:
+//
+//	server.SendEarlyHints(r, "</style.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script")
+func SendEarlyHints(r *request.Request, links ...string) error {
+	h := headers.NewHeaders()
+	for _, link := range links {
+		h.Add("link", link)
+	}
+	return SendInformational(r, response.StatusEarlyHints, h)
+}
+
+const (
+	continuePending int32 = iota
+	continueSent
+	continueSkipped
+)
+
+// expectContinue sends the interim 100 Continue response of a request at most once,
+// and only until the final response is written.
+type expectContinue struct {
+	iw    *interimWriter
+	state atomic.Int32
+}
+
+// send writes the 100 Continue response, unless it was already sent or it's too late.
+func (e *expectContinue) send() error {
+	if !e.state.CompareAndSwap(continuePending, continueSent) {
+		return nil
+	}
+	return e.iw.write(response.StatusContinue, nil)
+}
+
+// finish prevents 100 Continue from being sent after the final response, and reports
+// whether it was sent. If it wasn't, the client may or may not send the body and the
+// connection can't be reused.
+func (e *expectContinue) finish() bool {
+	e.state.CompareAndSwap(continuePending, continueSkipped)
+	return e.state.Load() == continueSent
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"log"
+	"net"
 	"runtime/debug"
 	"time"
 
@@ -32,6 +33,20 @@ type ServerOpts struct {
 	KeepAliveTimeout time.Duration
 
 	SizeLimits *request.SizeLimits
+
+	// PreserveHeaderCase writes response header names in the case they were set with
+	// (e.g. `Content-Type`) instead of lowercase, for clients that depend on it.
+	PreserveHeaderCase bool
+
+	// TLS enables TLS termination. If nil, the server serves plain HTTP.
+	TLS *TLSOptions
+
+	// DisableBanner turns off the banner printed when the server starts.
+	DisableBanner bool
+
+	// OnReady is called with the bound address once the server is accepting connections.
+	// See also [Server.Ready].
+	OnReady func(addr net.Addr)
 }
 
 var defaultRecovery = func(r any) response.Response {
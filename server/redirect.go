@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+)
+
+// HSTSOptions configures the Strict-Transport-Security header.
+type HSTSOptions struct {
+	// MaxAge is how long browsers should only use HTTPS for the host. Rounded down to seconds.
+	MaxAge time.Duration
+
+	// IncludeSubdomains applies the policy to all subdomains of the host too.
+	IncludeSubdomains bool
+
+	// Preload signals consent to be included in the browsers' HSTS preload lists.
+	Preload bool
+}
+
+// Header returns the value of the Strict-Transport-Security header.
+func (o HSTSOptions) Header() string {
+	value := "max-age=" + strconv.FormatInt(int64(o.MaxAge/time.Second), 10)
+	if o.IncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if o.Preload {
+		value += "; preload"
+	}
+	return value
+}
+
+// Handler returns a middleware-wrapped handler that adds the Strict-Transport-Security
+// header to every response. Browsers ignore the header on plain HTTP, so use it on the
+// HTTPS server.
+func (o HSTSOptions) Handler(next Handler) Handler {
+	value := o.Header()
+	return func(r *request.Request) response.Response {
+		return next(r).WithHeader("Strict-Transport-Security", value)
+	}
+}
+
+// HTTPSRedirectOptions configures the redirects sent by [HTTPSRedirectHandler]. Browsers
+// ignore Strict-Transport-Security on plain HTTP, so the redirects don't send it: add
+// [HSTSOptions.Handler] to the HTTPS server instead.
+type HTTPSRedirectOptions struct {
+	// Host is the host to redirect to. Defaults to the host of the request's Host header.
+	// Setting it avoids redirecting clients to hosts they made up.
+	Host string
+
+	// HTTPSPort is the port of the HTTPS server. Defaults to 443, which is left out of
+	// the redirect URL.
+	HTTPSPort int
+}
+
+// validRedirectHost reports whether host (without port) is a plain domain name or IP address.
+func validRedirectHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return net.ParseIP(host[1:len(host)-1]) != nil
+	}
+	for _, c := range host {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// stripPort removes the port from a Host header value, keeping IPv6 brackets.
+func stripPort(hostport string) string {
+	if i := strings.LastIndexByte(hostport, ':'); i != -1 && !strings.HasSuffix(hostport, "]") {
+		return hostport[:i]
+	}
+	return hostport
+}
+
+// redirectPath returns the path and query of the request target, in origin-form.
+func redirectPath(target string) string {
+	if i := strings.Index(target, "://"); i != -1 {
+		// absolute-form, drop the scheme and authority
+		rest := target[i+3:]
+		if j := strings.IndexAny(rest, "/?"); j != -1 {
+			target = rest[j:]
+		} else {
+			target = ""
+		}
+	}
+	if !strings.HasPrefix(target, "/") {
+		// asterisk-form or authority-form
+		return "/" + strings.TrimPrefix(strings.TrimPrefix(target, "*"), "/")
+	}
+	return target
+}
+
+// HTTPSRedirectHandler returns a handler that redirects every request to the same path and
+// query on the HTTPS origin, with a 308 Permanent Redirect that keeps the method and body.
+// Requests with an invalid Host header get a 400 Bad Request response.
+func HTTPSRedirectHandler(opts HTTPSRedirectOptions) Handler {
+	port := ""
+	if opts.HTTPSPort != 0 && opts.HTTPSPort != 443 {
+		port = ":" + strconv.Itoa(opts.HTTPSPort)
+	}
+
+	return func(r *request.Request) response.Response {
+		host := opts.Host
+		if host == "" {
+			host = stripPort(r.Headers.Get("host"))
+		}
+		if !validRedirectHost(host) {
+			return response.NewTextResponse("invalid host").WithStatusCode(response.StatusBadRequest)
+		}
+
+		return response.NewRedirectResponse("https://" + host + port + redirectPath(r.Target)).
+			WithStatusCode(response.StatusPermanentRedirect)
+	}
+}
+
+// NewHTTPSRedirectServer creates a plain HTTP server that redirects every request to the
+// HTTPS origin, see [HTTPSRedirectHandler]. It is meant to run next to the HTTPS server,
+// usually on port 80. opts.TLS is ignored.
+func NewHTTPSRedirectServer(opts ServerOpts, redirect HTTPSRedirectOptions) *Server {
+	opts.TLS = nil
+	return NewServer(opts, HTTPSRedirectHandler(redirect))
+}
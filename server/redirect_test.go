@@ -0,0 +1,68 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPSRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     HTTPSRedirectOptions
+		target   string
+		host     string
+		status   response.StatusCode
+		location string
+	}{
+		{"default port", HTTPSRedirectOptions{}, "/a/b?x=1", "example.com:8080", response.StatusPermanentRedirect, "https://example.com/a/b?x=1"},
+		{"custom port", HTTPSRedirectOptions{HTTPSPort: 8443}, "/", "example.com", response.StatusPermanentRedirect, "https://example.com:8443/"},
+		{"fixed host", HTTPSRedirectOptions{Host: "canonical.test"}, "/x", "evil.test", response.StatusPermanentRedirect, "https://canonical.test/x"},
+		{"ipv6 host", HTTPSRedirectOptions{}, "/", "[::1]:80", response.StatusPermanentRedirect, "https://[::1]/"},
+		{"absolute form", HTTPSRedirectOptions{}, "http://example.com/p?q", "example.com", response.StatusPermanentRedirect, "https://example.com/p?q"},
+		{"asterisk form", HTTPSRedirectOptions{}, "*", "example.com", response.StatusPermanentRedirect, "https://example.com/"},
+		{"invalid host", HTTPSRedirectOptions{}, "/", "evil.test/path", response.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := "GET " + tt.target + " HTTP/1.1\r\nHost: " + tt.host + "\r\n\r\n"
+			resp := HTTPSRedirectHandler(tt.opts)(parseRequest(t, raw))
+			assert.Equal(t, tt.status, resp.GetStatusCode())
+			assert.Equal(t, tt.location, resp.GetHeaders().Get("location"))
+			assert.Empty(t, resp.GetHeaders().Get("strict-transport-security"))
+		})
+	}
+}
+
+func TestHTTPSRedirectServer(t *testing.T) {
+	s := NewHTTPSRedirectServer(ServerOpts{Address: "127.0.0.1:0", DisableBanner: true}, HTTPSRedirectOptions{
+		HTTPSPort: 8443,
+	})
+	go s.ListenAndServe()
+	t.Cleanup(func() { s.Close() })
+	<-s.Ready()
+
+	conn, br := dial(t, s.Addr().String())
+	_, err := conn.Write([]byte("POST /submit HTTP/1.1\r\nHost: localhost:8080\r\nContent-Length: 0\r\n\r\n"))
+	require.NoError(t, err)
+
+	res, _ := readResponse(t, br)
+	assert.Equal(t, 308, res.StatusCode)
+	assert.Equal(t, "https://localhost:8443/submit", res.Header.Get("Location"))
+	assert.Empty(t, res.Header.Get("Strict-Transport-Security"))
+}
+
+func TestHSTSHandler(t *testing.T) {
+	hsts := HSTSOptions{MaxAge: time.Hour, Preload: true}
+	handler := hsts.Handler(func(r *request.Request) response.Response {
+		return response.NewTextResponse("ok")
+	})
+
+	resp := handler(nil)
+	assert.Equal(t, "max-age=3600; preload", resp.GetHeaders().Get("strict-transport-security"))
+}
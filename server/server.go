@@ -1,11 +1,15 @@
 package server
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
 	_ "embed"
 	"fmt"
+	"io"
 	"log"
 	"net"
-	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -16,28 +20,126 @@ import (
 //go:embed banner.txt
 var banner string
 
+// Server is an HTTP/1.1 server. Create one with [NewServer] or [Serve].
 type Server struct {
-	opts     ServerOpts
-	listener net.Listener
-	closed   atomic.Bool
-	handler  Handler
+	opts    ServerOpts
+	closed  atomic.Bool
+	handler Handler
+
+	// listener is set once the server is bound
+	listenerMu sync.Mutex
+	listener   net.Listener
+	ready      chan struct{}
+	readyOnce  sync.Once
+
+	// connection tracking for graceful shutdown
+	connsMu    sync.Mutex
+	conns      map[*trackedConn]struct{}
+	drained    int
+	inShutdown atomic.Bool
+
+	// TLS configuration, set up before serving
+	tlsOnce      sync.Once
+	tlsConfig    *tls.Config
+	certReloader *certReloader
+	tlsErr       error
+
+	// base context of all connections, cancelled on Close
+	baseCtx    context.Context
+	cancelBase context.CancelFunc
 }
 
-// Shutdown the server.
+// Close shuts down the server immediately. The contexts of all in-flight requests are cancelled.
+// Use [Server.Shutdown] to let in-flight requests finish.
 func (s *Server) Close() error {
 	s.closed.Store(true)
+	s.cancelBase()
+	return s.closeListener()
+}
+
+func (s *Server) closeListener() error {
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
 	return s.listener.Close()
 }
 
-func (s *Server) listen() error {
+// Ready returns a channel that is closed once the server is bound and accepting connections.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Addr returns the address the server is bound to, or nil if it isn't bound yet.
+// This is useful to find the actual port when listening on port 0.
+func (s *Server) Addr() net.Addr {
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// ListenAndServe binds to the configured address and serves connections.
+// It blocks until the server is closed, in which case it returns [ErrServerClosed].
+func (s *Server) ListenAndServe() error {
+	if s.closed.Load() {
+		return ErrServerClosed
+	}
 	listener, err := net.Listen("tcp", s.opts.Address)
 	if err != nil {
 		return err
 	}
+	return s.ServeListener(listener)
+}
+
+// ServeListener serves connections accepted on the given listener, which may be pre-bound.
+// If TLS is configured, connections are wrapped in TLS, so the listener should be a plain one.
+// The listener is closed when the server is closed, or right away if the server is already
+// serving, in which case [ErrAlreadyServing] is returned.
+// It blocks until the server is closed, in which case it returns [ErrServerClosed].
+func (s *Server) ServeListener(listener net.Listener) error {
+	if err := s.setupTLS(); err != nil {
+		listener.Close()
+		return err
+	}
+
+	s.listenerMu.Lock()
+	if s.closed.Load() {
+		s.listenerMu.Unlock()
+		listener.Close()
+		return ErrServerClosed
+	}
+	if s.listener != nil {
+		s.listenerMu.Unlock()
+		listener.Close()
+		return ErrAlreadyServing
+	}
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
 	s.listener = listener
+	s.listenerMu.Unlock()
+
+	s.readyOnce.Do(func() {
+		if !s.opts.DisableBanner {
+			fmt.Println(banner)
+		}
+		if s.opts.OnReady != nil {
+			s.opts.OnReady(listener.Addr())
+		}
+		close(s.ready)
+	})
+
+	return s.serve(listener)
+}
 
+// serve accepts connections on the listener until it is closed.
+func (s *Server) serve(listener net.Listener) error {
 	for {
-		conn, err := s.listener.Accept()
+		conn, err := listener.Accept()
 		if err != nil {
 			if !s.closed.Load() {
 				log.Println("unable to accept connection: " + err.Error())
@@ -52,18 +154,74 @@ func (s *Server) listen() error {
 			continue
 		}
 
-		if s.opts.ReadTimeout != 0 {
-			conn.SetReadDeadline(time.Now().Add(s.opts.ReadTimeout))
-		}
-		if s.opts.WriteTimeout != 0 {
-			conn.SetWriteDeadline(time.Now().Add(s.opts.WriteTimeout))
+		// track the connection before handing it off, so Shutdown can't miss it
+		connCtx, cancelConn := context.WithCancel(s.baseCtx)
+		tc := &trackedConn{Conn: conn, cancel: cancelConn}
+		s.trackConn(tc)
+		if s.inShutdown.Load() {
+			// accepted as the listener was closing
+			tc.closeIfIdle()
 		}
-		go s.handle(conn)
+		go s.handle(connCtx, tc)
+	}
+	return ErrServerClosed
+}
+
+// requestContext derives the context of a single request from the connection context.
+// The request context expires along with the write deadline, if there's one.
+func requestContext(connCtx context.Context, writeDeadline time.Time) (context.Context, context.CancelFunc) {
+	if writeDeadline.IsZero() {
+		return context.WithCancel(connCtx)
+	}
+	return context.WithDeadline(connCtx, writeDeadline)
+}
+
+// cleanupRequest releases the resources held by a request once its response has been written.
+func cleanupRequest(req *request.Request) {
+	if err := req.Cleanup(); err != nil {
+		log.Println("unable to clean up request:", err)
 	}
-	return nil
 }
 
-func (s *Server) handle(conn net.Conn) {
+// http10MaxBufferedBody is the largest chunked body buffered to be sent with a Content-Length
+// to HTTP/1.0 clients. Larger bodies are delimited by closing the connection.
+const http10MaxBufferedBody = 1 << 20
+
+// handle serves the requests of a connection tracked by serve, connCtx is cancelled by
+// conn.cancel.
+func (s *Server) handle(connCtx context.Context, conn *trackedConn) {
+	cancelConn := conn.cancel
+	defer cancelConn()
+	defer s.untrackConn(conn)
+	netConn := conn.Conn
+
+	cr := newConnReader(conn, cancelConn)
+	br := bufio.NewReader(cr)
+
+	var writeDeadline time.Time
+	if s.opts.ReadTimeout != 0 {
+		cr.setReadDeadline(time.Now().Add(s.opts.ReadTimeout))
+	}
+	if s.opts.WriteTimeout != 0 {
+		writeDeadline = time.Now().Add(s.opts.WriteTimeout)
+		conn.SetWriteDeadline(writeDeadline)
+	}
+
+	var tlsState *tls.ConnectionState
+	if tlsConn, ok := netConn.(*tls.Conn); ok {
+		if s.opts.ReadTimeout == 0 && s.opts.KeepAliveTimeout != 0 {
+			// don't let a client stall the handshake forever
+			tlsConn.SetReadDeadline(time.Now().Add(s.opts.KeepAliveTimeout))
+		}
+		if err := tlsConn.HandshakeContext(connCtx); err != nil {
+			log.Printf("TLS handshake error from %s: %v", netConn.RemoteAddr(), err)
+			conn.Close()
+			return
+		}
+		state := tlsConn.ConnectionState()
+		tlsState = &state
+	}
+
 	shouldCloseConn := false
 	if s.opts.KeepAliveTimeout == 0 {
 		shouldCloseConn = true
@@ -72,44 +230,82 @@ func (s *Server) handle(conn net.Conn) {
 	// defers are stacked
 
 	defer func() {
-		if conn != nil && shouldCloseConn {
-			if err := conn.Close(); err != nil {
-				log.Println("unable to close connection", err)
-			}
+		if err := conn.Close(); err != nil {
+			log.Println("unable to close connection", err)
 		}
 	}()
 
+	// request being handled, cleaned up if its handler panics
+	var current *request.Request
 	defer func() {
 		if r := recover(); r != nil {
 			resp := s.opts.Recovery(r)
 			resp.Write(conn)
-			conn.Close()
+			if current != nil {
+				cleanupRequest(current)
+			}
 			return
 		}
 	}()
 
 	for {
 		if s.opts.KeepAliveTimeout != 0 {
-			if conn != nil {
-				conn.SetDeadline(time.Now().Add(s.opts.KeepAliveTimeout))
-			}
+			deadline := time.Now().Add(s.opts.KeepAliveTimeout)
+			cr.setReadDeadline(deadline)
+			writeDeadline = deadline
+			conn.SetWriteDeadline(writeDeadline)
+		}
+
+		// wait for the next request while idle
+		if _, err := br.Peek(1); err != nil {
+			break
+		}
+		if !conn.setState(stateActive) {
+			// closed by shutdown
+			break
 		}
 
 		badReqResponse := response.NewBaseResponse().WithStatusCode(response.StatusBadRequest)
-		req, err := request.RequestFromReader(conn, s.opts.SizeLimits)
+		req, err := request.RequestFromReader(br, s.opts.SizeLimits)
 		if err != nil {
 			// invalid request
 			badReqResponse.Write(conn)
-			shouldCloseConn = true
 			break
 		}
 
+		current = req
+		req.TLS = tlsState
+		reqCtx, cancelReq := requestContext(connCtx, writeDeadline)
+		req.SetContext(reqCtx)
+		if br.Buffered() == 0 {
+			// nothing pipelined, watch for the client going away
+			cr.startBackgroundRead()
+		}
+
+		interim := &interimWriter{w: conn}
+		request.SetValue(req, interimWriterKey, interim)
+		var expect *expectContinue
+		if req.ExpectsContinue() {
+			expect = &expectContinue{iw: interim}
+			req.SetContinueFunc(expect.send)
+		}
 
 		resp := s.handler(req)
+		interim.finish()
+		if closer, ok := resp.GetBody().(io.Closer); ok {
+			// unblock bodies still being read when the client goes away, and end streams once
+			// the response is written
+			context.AfterFunc(reqCtx, func() { closer.Close() })
+		}
+		if expect != nil && !expect.finish() {
+			// the body was neither requested nor sent, it can't be skipped reliably
+			shouldCloseConn = true
+		}
 		resp.GetHeaders().Remove("date")
-		resp.WithHeader("date", time.Now().Format(time.RFC1123))
-		if shouldCloseConn {
-			resp.WithHeader("connection", "close")
+		resp.WithHeader("Date", time.Now().Format(time.RFC1123))
+		if s.inShutdown.Load() || !req.WantsKeepAlive() {
+			// tell the client not to reuse the connection
+			shouldCloseConn = true
 		}
 
 		if respEtag, reqEtag := resp.GetHeaders().Get("etag"), req.Headers.Get("if-none-match"); respEtag != "" && reqEtag != "" {
@@ -122,16 +318,38 @@ func (s *Server) handle(conn net.Conn) {
 			}
 		}
 
+		if req.IsHTTP10() {
+			var closeDelimited bool
+			resp, closeDelimited, err = response.ForHTTP10(resp, http10MaxBufferedBody)
+			if err != nil {
+				log.Println("unable to buffer response body:", err)
+				cr.abortPendingRead()
+				cancelReq()
+				cleanupRequest(req)
+				break
+			}
+			if closeDelimited {
+				shouldCloseConn = true
+			} else if !shouldCloseConn {
+				// HTTP/1.0 clients only reuse connections when told so
+				resp.WithHeader("Connection", "keep-alive")
+			}
+		}
+		if shouldCloseConn {
+			resp.WithHeader("Connection", "close")
+		}
+		if s.opts.PreserveHeaderCase {
+			resp.GetHeaders().SetPreserveCase(true)
+		}
+
 		err = resp.Write(conn)
+		cr.abortPendingRead()
+		cancelReq()
+		cleanupRequest(req)
+		current = nil
 		if err != nil {
 			log.Println("unable to write response to connection:", err)
-			shouldCloseConn = true
-			break
-		}
-
-		if strings.TrimSpace(strings.ToLower(req.Headers.Get("connection"))) == "close" {
-			// if the client requests connection close, respect it
-			shouldCloseConn = true
+			cancelConn()
 			break
 		}
 
@@ -142,10 +360,16 @@ func (s *Server) handle(conn net.Conn) {
 		// this error is already checked via the transfer encoding check
 		b, _ := req.Body()
 		b.Close() // discard body from buffer
+
+		if !conn.setState(stateIdle) || s.inShutdown.Load() {
+			break
+		}
 	}
 }
 
-func newServer(opts ServerOpts, handler Handler) *Server {
+// NewServer creates a server with the given options and handler.
+// Start it with [Server.ListenAndServe] or [Server.ServeListener].
+func NewServer(opts ServerOpts, handler Handler) *Server {
 	if opts.Recovery == nil {
 		opts.Recovery = defaultRecovery
 	}
@@ -156,30 +380,35 @@ func newServer(opts ServerOpts, handler Handler) *Server {
 		opts.SizeLimits = &request.DefaultSizeLimits
 	}
 
+	baseCtx, cancelBase := context.WithCancel(context.Background())
 	return &Server{
-		opts:    opts,
-		handler: handler,
+		opts:       opts,
+		handler:    handler,
+		ready:      make(chan struct{}),
+		baseCtx:    baseCtx,
+		cancelBase: cancelBase,
 	}
 }
 
-// Starts the HTTP server with the given options and handler.
+// Serve starts the HTTP server with the given options and handler in the background.
+// It returns once the server is bound to its address, or with the error that prevented it.
 func Serve(opts ServerOpts, handler Handler) (*Server, error) {
-	fmt.Println(banner)
-	s := newServer(opts, handler)
+	s := NewServer(opts, handler)
+
+	listener, err := net.Listen("tcp", s.opts.Address)
+	if err != nil {
+		return nil, err
+	}
 
 	errCh := make(chan error, 1)
 	go func() {
-		err := s.listen()
-		if err != nil {
-			errCh <- err
-		}
+		errCh <- s.ServeListener(listener)
 	}()
 
-	// give the server a moment to start and potentially fail
 	select {
+	case <-s.Ready():
+		return s, nil
 	case err := <-errCh:
 		return nil, err
-	case <-time.After(100 * time.Millisecond):
-		return s, nil
 	}
 }
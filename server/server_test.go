@@ -0,0 +1,554 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestServer starts a server on a random local port and returns it along with its address.
+func startTestServer(t *testing.T, opts ServerOpts, handler Handler) (*Server, string) {
+	t.Helper()
+
+	opts.Address = "127.0.0.1:0"
+	opts.DisableBanner = true
+	s := NewServer(opts, handler)
+	go s.ListenAndServe()
+	t.Cleanup(func() { s.Close() })
+
+	select {
+	case <-s.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("server did not become ready")
+	}
+	return s, s.Addr().String()
+}
+
+func dial(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, bufio.NewReader(conn)
+}
+
+func readResponse(t *testing.T, br *bufio.Reader) (*http.Response, string) {
+	t.Helper()
+	res, err := http.ReadResponse(br, nil)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, string(body)
+}
+
+// parseRequest parses a raw request, as read from a connection, for calling handlers directly.
+func parseRequest(t *testing.T, raw string) *request.Request {
+	t.Helper()
+	r, err := request.RequestFromReader(strings.NewReader(raw), nil)
+	require.NoError(t, err)
+	return r
+}
+
+// readBody reads the body of a response returned by a handler.
+func readBody(t *testing.T, resp response.Response) string {
+	t.Helper()
+	body, err := io.ReadAll(resp.GetBody())
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestServerKeepAlivePipelining(t *testing.T) {
+	_, addr := startTestServer(t, ServerOpts{KeepAliveTimeout: time.Second}, func(r *request.Request) response.Response {
+		return response.NewTextResponse("hello " + r.Target)
+	})
+
+	conn, br := dial(t, addr)
+	_, err := conn.Write([]byte("GET /a HTTP/1.1\r\nHost: x\r\n\r\nGET /b HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+
+	_, body := readResponse(t, br)
+	assert.Equal(t, "hello /a", body)
+	_, body = readResponse(t, br)
+	assert.Equal(t, "hello /b", body)
+}
+
+func TestServerCancelsContextOnDisconnect(t *testing.T) {
+	cancelled := make(chan error, 1)
+	_, addr := startTestServer(t, ServerOpts{}, func(r *request.Request) response.Response {
+		select {
+		case <-r.Context().Done():
+			cancelled <- r.Context().Err()
+		case <-time.After(2 * time.Second):
+			cancelled <- nil
+		}
+		return response.NewBaseResponse()
+	})
+
+	conn, _ := dial(t, addr)
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	conn.Close()
+
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+}
+
+func TestServerStopsStreamOnDisconnect(t *testing.T) {
+	stopped := make(chan error, 1)
+	_, addr := startTestServer(t, ServerOpts{}, func(r *request.Request) response.Response {
+		return response.NewStreamResponse(func(w io.Writer, setTrailer response.TrailerSetter) error {
+			if _, err := w.Write([]byte("first")); err != nil {
+				stopped <- err
+				return err
+			}
+			// a stream waiting on something else than w
+			time.Sleep(200 * time.Millisecond)
+			_, err := w.Write([]byte("second"))
+			stopped <- err
+			return err
+		}, nil)
+	})
+
+	conn, br := dial(t, addr)
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+	res, err := http.ReadResponse(br, nil)
+	require.NoError(t, err)
+	_, err = res.Body.Read(make([]byte, 5))
+	require.NoError(t, err)
+	conn.Close()
+
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream was not stopped")
+	}
+}
+
+func TestServerClosesBodyOnDisconnect(t *testing.T) {
+	stopped := make(chan error, 1)
+	_, addr := startTestServer(t, ServerOpts{}, func(r *request.Request) response.Response {
+		// any closable body, not only stream responses
+		pr, pw := io.Pipe()
+		go func() {
+			pw.Write([]byte("first"))
+			time.Sleep(200 * time.Millisecond)
+			_, err := pw.Write([]byte("second"))
+			stopped <- err
+		}()
+		return response.NewBaseResponse().WithBody(pr)
+	})
+
+	conn, br := dial(t, addr)
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+	_, err = http.ReadResponse(br, nil)
+	require.NoError(t, err)
+	conn.Close()
+
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+	case <-time.After(2 * time.Second):
+		t.Fatal("body was not closed")
+	}
+}
+
+func TestServerShutdownDrainsActiveConnections(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s, addr := startTestServer(t, ServerOpts{KeepAliveTimeout: 5 * time.Second}, func(r *request.Request) response.Response {
+		if r.Target == "/slow" {
+			close(started)
+			<-release
+		}
+		return response.NewTextResponse("done")
+	})
+
+	// an idle keep-alive connection
+	idleConn, idleBr := dial(t, addr)
+	_, err := idleConn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+	readResponse(t, idleBr)
+
+	// an active connection
+	activeConn, activeBr := dial(t, addr)
+	_, err = activeConn.Write([]byte("GET /slow HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+	<-started
+
+	type result struct {
+		stats ShutdownStats
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		stats, err := s.Shutdown(context.Background())
+		done <- result{stats, err}
+	}()
+
+	// idle connection is closed right away
+	idleConn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = idleBr.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// new connections are refused
+	_, err = net.DialTimeout("tcp", addr, 100*time.Millisecond)
+	assert.Error(t, err)
+
+	close(release)
+	res, body := readResponse(t, activeBr)
+	assert.Equal(t, "done", body)
+	assert.True(t, res.Close, "expected connection: close")
+
+	r := <-done
+	require.NoError(t, r.err)
+	assert.Equal(t, ShutdownStats{Drained: 2, Killed: 0}, r.stats)
+}
+
+// handoffListener hands out a single connection, then blocks until closed.
+type handoffListener struct {
+	conn     net.Conn
+	accepted chan struct{}
+	closed   chan struct{}
+}
+
+func (l *handoffListener) Accept() (net.Conn, error) {
+	if conn := l.conn; conn != nil {
+		l.conn = nil
+		return conn, nil
+	}
+	close(l.accepted)
+	<-l.closed
+	return nil, net.ErrClosed
+}
+
+func (l *handoffListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func (l *handoffListener) Addr() net.Addr { return &net.TCPAddr{} }
+
+func TestServerTracksConnectionsOnAccept(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	listener := &handoffListener{conn: serverConn, accepted: make(chan struct{}), closed: make(chan struct{})}
+
+	s := NewServer(ServerOpts{DisableBanner: true, KeepAliveTimeout: time.Second}, func(r *request.Request) response.Response {
+		return response.NewBaseResponse()
+	})
+	go s.ServeListener(listener)
+
+	// the connection is tracked before the next Accept, not whenever its goroutine runs
+	<-listener.accepted
+	s.connsMu.Lock()
+	tracked := len(s.conns)
+	s.connsMu.Unlock()
+	assert.Equal(t, 1, tracked)
+
+	stats, err := s.Shutdown(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ShutdownStats{Drained: 1}, stats)
+}
+
+func TestServerShutdownKillsOnTimeout(t *testing.T) {
+	started := make(chan struct{})
+	handlerCtxErr := make(chan error, 1)
+	s, addr := startTestServer(t, ServerOpts{}, func(r *request.Request) response.Response {
+		close(started)
+		<-r.Context().Done()
+		handlerCtxErr <- r.Context().Err()
+		return response.NewBaseResponse()
+	})
+
+	conn, _ := dial(t, addr)
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	stats, err := s.Shutdown(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, ShutdownStats{Drained: 0, Killed: 1}, stats)
+	assert.ErrorIs(t, <-handlerCtxErr, context.Canceled)
+}
+
+func TestServerServeListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var readyAddr net.Addr
+	s := NewServer(ServerOpts{
+		DisableBanner: true,
+		OnReady:       func(addr net.Addr) { readyAddr = addr },
+	}, func(r *request.Request) response.Response {
+		return response.NewTextResponse("pre-bound")
+	})
+	assert.Nil(t, s.Addr())
+
+	served := make(chan error, 1)
+	go func() { served <- s.ServeListener(listener) }()
+	<-s.Ready()
+
+	assert.Equal(t, listener.Addr(), s.Addr())
+	assert.Equal(t, listener.Addr(), readyAddr)
+
+	conn, br := dial(t, s.Addr().String())
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+	_, body := readResponse(t, br)
+	assert.Equal(t, "pre-bound", body)
+
+	// a second listener is refused and closed
+	second, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.ServeListener(second), ErrAlreadyServing)
+	_, err = second.Accept()
+	assert.ErrorIs(t, err, net.ErrClosed)
+
+	require.NoError(t, s.Close())
+	assert.ErrorIs(t, <-served, ErrServerClosed)
+
+	// serving again after close is refused
+	assert.ErrorIs(t, s.ListenAndServe(), ErrServerClosed)
+}
+
+func TestServerListenAndServeError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// address already in use
+	s := NewServer(ServerOpts{Address: listener.Addr().String(), DisableBanner: true}, nil)
+	assert.Error(t, s.ListenAndServe())
+
+	_, err = Serve(ServerOpts{Address: listener.Addr().String(), DisableBanner: true}, nil)
+	assert.Error(t, err)
+}
+
+func TestServeRandomPort(t *testing.T) {
+	s, err := Serve(ServerOpts{Address: "127.0.0.1:0", DisableBanner: true}, func(r *request.Request) response.Response {
+		return response.NewTextResponse("ok")
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	addr, ok := s.Addr().(*net.TCPAddr)
+	require.True(t, ok)
+	assert.NotZero(t, addr.Port)
+
+	conn, br := dial(t, addr.String())
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+	_, body := readResponse(t, br)
+	assert.Equal(t, "ok", body)
+}
+
+func TestServerHTTP10(t *testing.T) {
+	_, addr := startTestServer(t, ServerOpts{KeepAliveTimeout: time.Second}, func(r *request.Request) response.Response {
+		if r.Target == "/stream" {
+			return response.NewStreamResponse(func(w io.Writer, setTrailer response.TrailerSetter) error {
+				_, err := w.Write([]byte("streamed"))
+				return err
+			}, nil)
+		}
+		return response.NewTextResponse("hello " + r.Target)
+	})
+
+	// closed by default
+	conn, br := dial(t, addr)
+	_, err := conn.Write([]byte("GET /a HTTP/1.0\r\n\r\n"))
+	require.NoError(t, err)
+	res, body := readResponse(t, br)
+	assert.Equal(t, "HTTP/1.0", res.Proto)
+	assert.Equal(t, "hello /a", body)
+	assert.True(t, res.Close)
+	_, err = br.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// kept alive on request
+	conn, br = dial(t, addr)
+	_, err = conn.Write([]byte("GET /a HTTP/1.0\r\nConnection: keep-alive\r\n\r\nGET /stream HTTP/1.0\r\nConnection: keep-alive\r\n\r\n"))
+	require.NoError(t, err)
+	res, body = readResponse(t, br)
+	assert.Equal(t, "hello /a", body)
+	assert.False(t, res.Close)
+	assert.Equal(t, "keep-alive", res.Header.Get("Connection"))
+
+	// streams are never chunked, the connection delimits the body
+	res, body = readResponse(t, br)
+	assert.Equal(t, "HTTP/1.0", res.Proto)
+	assert.Empty(t, res.TransferEncoding)
+	assert.Equal(t, "streamed", body)
+	assert.True(t, res.Close)
+}
+
+func TestServerExpectContinue(t *testing.T) {
+	_, addr := startTestServer(t, ServerOpts{KeepAliveTimeout: time.Second}, func(r *request.Request) response.Response {
+		if r.ContentLength() > 10 {
+			return response.NewTextResponse("too large").WithStatusCode(response.StatusExpectationFailed)
+		}
+		body, err := r.Body()
+		require.NoError(t, err)
+		data, err := io.ReadAll(body)
+		require.NoError(t, err)
+		return response.NewTextResponse("got " + string(data))
+	})
+
+	// the interim response is sent once the handler reads the body
+	conn, br := dial(t, addr)
+	_, err := conn.Write([]byte("POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\nExpect: 100-continue\r\n\r\n"))
+	require.NoError(t, err)
+	res, _ := readResponse(t, br)
+	assert.Equal(t, 100, res.StatusCode)
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	res, body := readResponse(t, br)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "got hello", body)
+	assert.False(t, res.Close)
+
+	// rejected before the body is sent, the connection can't be reused
+	_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 100\r\nExpect: 100-continue\r\n\r\n"))
+	require.NoError(t, err)
+	res, body = readResponse(t, br)
+	assert.Equal(t, 417, res.StatusCode)
+	assert.Equal(t, "too large", body)
+	assert.True(t, res.Close)
+	_, err = br.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestServerEarlyHints(t *testing.T) {
+	hintErrs := make(chan error, 1)
+	_, addr := startTestServer(t, ServerOpts{}, func(r *request.Request) response.Response {
+		hintErrs <- SendEarlyHints(r, "</style.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script")
+		return response.NewTextResponse("page")
+	})
+
+	conn, br := dial(t, addr)
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+
+	res, _ := readResponse(t, br)
+	assert.Equal(t, 103, res.StatusCode)
+	assert.Equal(t, []string{"</style.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script"}, res.Header.Values("Link"))
+	res, body := readResponse(t, br)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "page", body)
+	assert.NoError(t, <-hintErrs)
+
+	// HTTP/1.0 clients never get informational responses
+	conn, br = dial(t, addr)
+	_, err = conn.Write([]byte("GET / HTTP/1.0\r\n\r\n"))
+	require.NoError(t, err)
+	res, body = readResponse(t, br)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "page", body)
+	assert.NoError(t, <-hintErrs)
+}
+
+func TestSendInformationalErrors(t *testing.T) {
+	req := parseRequest(t, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+	assert.ErrorIs(t, SendEarlyHints(req, "</a.css>; rel=preload"), ErrNotServed)
+
+	iw := &interimWriter{w: io.Discard}
+	request.SetValue(req, interimWriterKey, iw)
+	assert.NoError(t, SendInformational(req, response.StatusProcessing, nil))
+	iw.finish()
+	assert.ErrorIs(t, SendInformational(req, response.StatusProcessing, nil), ErrFinalResponseWritten)
+}
+
+func TestServerPreserveHeaderCase(t *testing.T) {
+	_, addr := startTestServer(t, ServerOpts{PreserveHeaderCase: true}, func(r *request.Request) response.Response {
+		return response.NewBaseResponse().WithHeader("X-Request-ID", "abc")
+	})
+
+	conn, br := dial(t, addr)
+	_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+
+	raw, err := io.ReadAll(br)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "\r\nX-Request-ID: abc\r\n")
+	assert.Contains(t, string(raw), "\r\nConnection: close\r\n")
+}
+
+func TestServerRemovesMultipartTempFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+
+	spooled := make(chan int, 1)
+	_, addr := startTestServer(t, ServerOpts{SizeLimits: &request.SizeLimits{MultipartMemory: 4}}, func(r *request.Request) response.Response {
+		form, err := r.MultipartForm()
+		if err != nil {
+			return response.NewTextResponse(err.Error()).WithStatusCode(response.StatusBadRequest)
+		}
+		entries, _ := os.ReadDir(tmpDir)
+		spooled <- len(entries)
+		return response.NewTextResponse(form.File["book"][0].Filename)
+	})
+
+	body := "--b\r\nContent-Disposition: form-data; name=\"book\"; filename=\"hobbit.txt\"\r\n\r\nIn a hole in the ground\r\n--b--\r\n"
+	conn, br := dial(t, addr)
+	_, err := fmt.Fprintf(conn, "POST / HTTP/1.1\r\nHost: x\r\nContent-Type: multipart/form-data; boundary=b\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	require.NoError(t, err)
+
+	res, respBody := readResponse(t, br)
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "hobbit.txt", respBody)
+	assert.Equal(t, 1, <-spooled)
+
+	// the response is read before the server cleans up, poll briefly
+	assert.Eventually(t, func() bool {
+		entries, _ := os.ReadDir(tmpDir)
+		return len(entries) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServerRemovesMultipartTempFilesOnPanic(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+
+	spooled := make(chan int, 1)
+	_, addr := startTestServer(t, ServerOpts{SizeLimits: &request.SizeLimits{MultipartMemory: 4}}, func(r *request.Request) response.Response {
+		if _, err := r.MultipartForm(); err != nil {
+			return response.NewTextResponse(err.Error()).WithStatusCode(response.StatusBadRequest)
+		}
+		entries, _ := os.ReadDir(tmpDir)
+		spooled <- len(entries)
+		panic("handler failed")
+	})
+
+	body := "--b\r\nContent-Disposition: form-data; name=\"book\"; filename=\"hobbit.txt\"\r\n\r\nIn a hole in the ground\r\n--b--\r\n"
+	conn, br := dial(t, addr)
+	_, err := fmt.Fprintf(conn, "POST / HTTP/1.1\r\nHost: x\r\nContent-Type: multipart/form-data; boundary=b\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	require.NoError(t, err)
+
+	res, _ := readResponse(t, br)
+	assert.Equal(t, 500, res.StatusCode)
+	assert.Equal(t, 1, <-spooled)
+
+	assert.Eventually(t, func() bool {
+		entries, _ := os.ReadDir(tmpDir)
+		return len(entries) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+// shutdownPollInterval is how often Shutdown checks whether all connections have finished.
+const shutdownPollInterval = 50 * time.Millisecond
+
+// ShutdownStats reports what happened to the connections open when [Server.Shutdown] was called.
+type ShutdownStats struct {
+	// Drained is the number of connections that finished gracefully.
+	Drained int
+
+	// Killed is the number of connections that were force-closed when the context expired.
+	Killed int
+}
+
+// trackConn registers a new client connection with the server.
+func (s *Server) trackConn(tc *trackedConn) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	if s.conns == nil {
+		s.conns = make(map[*trackedConn]struct{})
+	}
+	s.conns[tc] = struct{}{}
+}
+
+// untrackConn removes a finished connection from the server.
+func (s *Server) untrackConn(tc *trackedConn) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	delete(s.conns, tc)
+
+	tc.mu.Lock()
+	killed := tc.killed
+	tc.mu.Unlock()
+	if s.inShutdown.Load() && !killed {
+		s.drained++
+	}
+}
+
+// closeIdleConns closes all connections waiting for their next request.
+// It reports whether no connections are left.
+func (s *Server) closeIdleConns() bool {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	for tc := range s.conns {
+		tc.closeIfIdle()
+	}
+	return len(s.conns) == 0
+}
+
+// killConns force-closes all remaining connections and returns how many there were.
+func (s *Server) killConns() int {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	for tc := range s.conns {
+		tc.kill()
+	}
+	return len(s.conns)
+}
+
+// Shutdown gracefully shuts down the server.
+// It stops accepting new connections and closes idle keep-alive connections right away.
+// Active connections finish their current request, and the response carries a
+// `Connection: close` header. Shutdown then waits for all handlers to return.
+//
+// If ctx expires first, the remaining connections are force-closed, their request
+// contexts are cancelled and ctx's error is returned.
+// The returned stats report how many connections were drained and how many were killed.
+func (s *Server) Shutdown(ctx context.Context) (ShutdownStats, error) {
+	s.inShutdown.Store(true)
+	s.closed.Store(true)
+
+	err := s.closeListener()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.closeIdleConns() {
+			s.cancelBase()
+			return s.shutdownStats(0), err
+		}
+
+		select {
+		case <-ctx.Done():
+			killed := s.killConns()
+			s.cancelBase()
+			return s.shutdownStats(killed), ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) shutdownStats(killed int) ShutdownStats {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	return ShutdownStats{Drained: s.drained, Killed: killed}
+}
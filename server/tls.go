@@ -0,0 +1,252 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultCertReloadInterval is how often certificate files are checked for changes.
+const defaultCertReloadInterval = 10 * time.Second
+
+// CertificateFiles is a PEM encoded certificate (chain) and private key pair on disk.
+type CertificateFiles struct {
+	CertFile string
+	KeyFile  string
+}
+
+// TLSOptions configures TLS termination.
+// Certificates are picked by the SNI server name sent by the client; the first matching
+// certificate wins, and the first certificate is used when none match.
+type TLSOptions struct {
+	// Certificates loaded from disk. The files are watched for changes and reloaded
+	// without a restart.
+	Certificates []CertificateFiles
+
+	// Config is the base TLS configuration. It is cloned before use.
+	// Its certificates are served after the ones in Certificates.
+	// If Config.GetCertificate or Config.GetConfigForClient is set, the server uses it as-is.
+	// Defaults to a config with TLS 1.2 as the minimum version.
+	Config *tls.Config
+
+	// ReloadInterval is the minimum time between two checks of the certificate files
+	// for changes. Defaults to 10 seconds, a negative value disables reloading.
+	ReloadInterval time.Duration
+
+	// ClientAuth is the policy for TLS client certificates. If zero, the policy of
+	// Config is used, which defaults to not asking for client certificates.
+	// Use [tls.RequestClientCert] or [tls.VerifyClientCertIfGiven] to check client
+	// certificates per route with the middleware package's ClientCertAuth, and
+	// [tls.RequireAndVerifyClientCert] to reject clients without a valid certificate
+	// during the handshake.
+	ClientAuth tls.ClientAuthType
+
+	// ClientCAs are the CAs used to verify client certificates during the handshake,
+	// when ClientAuth asks for verification.
+	ClientCAs *x509.CertPool
+}
+
+// certFileState is a certificate loaded from disk along with the file modification times
+// it was loaded at.
+type certFileState struct {
+	files       CertificateFiles
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// certReloader serves certificates by SNI and reloads them when their files change.
+type certReloader struct {
+	interval time.Duration
+	static   []tls.Certificate
+
+	mu        sync.RWMutex
+	files     []*certFileState
+	lastCheck time.Time
+}
+
+func modTimes(files CertificateFiles) (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(files.CertFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(files.KeyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+func loadCertFile(files CertificateFiles) (*certFileState, error) {
+	certModTime, keyModTime, err := modTimes(files)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(files.CertFile, files.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load certificate %s: %w", files.CertFile, err)
+	}
+	return &certFileState{
+		files:       files,
+		cert:        &cert,
+		certModTime: certModTime,
+		keyModTime:  keyModTime,
+	}, nil
+}
+
+func newCertReloader(files []CertificateFiles, static []tls.Certificate, interval time.Duration) (*certReloader, error) {
+	cr := &certReloader{interval: interval, static: static, lastCheck: time.Now()}
+	for _, f := range files {
+		state, err := loadCertFile(f)
+		if err != nil {
+			return nil, err
+		}
+		cr.files = append(cr.files, state)
+	}
+	return cr, nil
+}
+
+// reload reloads the certificates whose files changed since they were last loaded.
+// Certificates that fail to load keep being served from memory.
+func (cr *certReloader) reload() error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.lastCheck = time.Now()
+
+	var errs []error
+	for i, state := range cr.files {
+		certModTime, keyModTime, err := modTimes(state.files)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if certModTime.Equal(state.certModTime) && keyModTime.Equal(state.keyModTime) {
+			continue
+		}
+		newState, err := loadCertFile(state.files)
+		if err != nil {
+			// the files may be halfway through being replaced, retry on the next check
+			errs = append(errs, err)
+			continue
+		}
+		cr.files[i] = newState
+	}
+	return errors.Join(errs...)
+}
+
+// maybeReload reloads the certificates if the reload interval has passed.
+func (cr *certReloader) maybeReload() {
+	if cr.interval < 0 {
+		return
+	}
+	cr.mu.RLock()
+	due := time.Since(cr.lastCheck) >= cr.interval
+	cr.mu.RUnlock()
+	if !due {
+		return
+	}
+	if err := cr.reload(); err != nil {
+		log.Println("unable to reload certificates:", err)
+	}
+}
+
+// certificates returns all certificates, in order of preference.
+func (cr *certReloader) certificates() []*tls.Certificate {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	certs := make([]*tls.Certificate, 0, len(cr.files)+len(cr.static))
+	for _, state := range cr.files {
+		certs = append(certs, state.cert)
+	}
+	for i := range cr.static {
+		certs = append(certs, &cr.static[i])
+	}
+	return certs
+}
+
+// GetCertificate implements [tls.Config.GetCertificate].
+func (cr *certReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.maybeReload()
+
+	certs := cr.certificates()
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates configured")
+	}
+	for _, cert := range certs {
+		if hello.SupportsCertificate(cert) == nil {
+			return cert, nil
+		}
+	}
+	return certs[0], nil
+}
+
+// buildTLSConfig builds the TLS configuration from the options.
+// The returned reloader is nil if no certificate files are served.
+func buildTLSConfig(opts *TLSOptions) (*tls.Config, *certReloader, error) {
+	var config *tls.Config
+	if opts.Config != nil {
+		config = opts.Config.Clone()
+	} else {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if len(config.NextProtos) == 0 {
+		config.NextProtos = []string{"http/1.1"}
+	}
+	if opts.ClientAuth != tls.NoClientCert {
+		config.ClientAuth = opts.ClientAuth
+	}
+	if opts.ClientCAs != nil {
+		config.ClientCAs = opts.ClientCAs
+	}
+
+	if config.GetCertificate != nil || config.GetConfigForClient != nil {
+		if len(opts.Certificates) > 0 {
+			return nil, nil, errors.New("tls: certificate files can't be combined with a custom GetCertificate or GetConfigForClient")
+		}
+		return config, nil, nil
+	}
+
+	if len(opts.Certificates) == 0 && len(config.Certificates) == 0 {
+		return nil, nil, errors.New("tls: no certificates configured")
+	}
+
+	interval := opts.ReloadInterval
+	if interval == 0 {
+		interval = defaultCertReloadInterval
+	}
+	reloader, err := newCertReloader(opts.Certificates, config.Certificates, interval)
+	if err != nil {
+		return nil, nil, err
+	}
+	config.Certificates = nil
+	config.GetCertificate = reloader.GetCertificate
+	return config, reloader, nil
+}
+
+// setupTLS prepares the TLS configuration of the server, if TLS is enabled.
+func (s *Server) setupTLS() error {
+	s.tlsOnce.Do(func() {
+		if s.opts.TLS == nil {
+			return
+		}
+		s.tlsConfig, s.certReloader, s.tlsErr = buildTLSConfig(s.opts.TLS)
+	})
+	return s.tlsErr
+}
+
+// ReloadCertificates reloads the certificate files that changed on disk, without waiting
+// for the reload interval. Useful to react to a signal from a certificate renewal job.
+func (s *Server) ReloadCertificates() error {
+	if err := s.setupTLS(); err != nil {
+		return err
+	}
+	if s.certReloader == nil {
+		return nil
+	}
+	return s.certReloader.reload()
+}
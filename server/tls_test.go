@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shravanasati/shadowfax/request"
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCert writes a self-signed certificate for the given DNS names to dir
+// and returns the certificate and key file paths.
+func writeTestCert(t *testing.T, dir, name, commonName string, dnsNames ...string) CertificateFiles {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	files := CertificateFiles{
+		CertFile: filepath.Join(dir, name+".crt"),
+		KeyFile:  filepath.Join(dir, name+".key"),
+	}
+	require.NoError(t, os.WriteFile(files.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(files.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return files
+}
+
+// tlsGet performs a GET request over TLS with the given SNI and returns the peer
+// certificate's common name along with the response body.
+func tlsGet(t *testing.T, addr, serverName string) (string, string) {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: " + serverName + "\r\n\r\n"))
+	require.NoError(t, err)
+	_, body := readResponse(t, bufio.NewReader(conn))
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, body
+}
+
+func TestServerTLSWithSNI(t *testing.T) {
+	dir := t.TempDir()
+	defaultCert := writeTestCert(t, dir, "default", "default", "default.test")
+	apiCert := writeTestCert(t, dir, "api", "api", "api.test")
+
+	var gotTLS *tls.ConnectionState
+	_, addr := startTestServer(t, ServerOpts{
+		TLS: &TLSOptions{Certificates: []CertificateFiles{defaultCert, apiCert}},
+	}, func(r *request.Request) response.Response {
+		gotTLS = r.TLS
+		return response.NewTextResponse("secure")
+	})
+
+	cn, body := tlsGet(t, addr, "api.test")
+	assert.Equal(t, "api", cn)
+	assert.Equal(t, "secure", body)
+
+	require.NotNil(t, gotTLS)
+	assert.Equal(t, "api.test", gotTLS.ServerName)
+	assert.True(t, gotTLS.HandshakeComplete)
+	assert.GreaterOrEqual(t, gotTLS.Version, uint16(tls.VersionTLS12))
+
+	// unknown names fall back to the first certificate
+	cn, _ = tlsGet(t, addr, "unknown.test")
+	assert.Equal(t, "default", cn)
+}
+
+func TestServerTLSCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	files := writeTestCert(t, dir, "site", "old", "site.test")
+
+	s, addr := startTestServer(t, ServerOpts{
+		TLS: &TLSOptions{Certificates: []CertificateFiles{files}, ReloadInterval: -1},
+	}, func(r *request.Request) response.Response {
+		return response.NewBaseResponse()
+	})
+
+	cn, _ := tlsGet(t, addr, "site.test")
+	assert.Equal(t, "old", cn)
+
+	// replace the files on disk, with a distinct modification time
+	writeTestCert(t, dir, "site", "new", "site.test")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(files.CertFile, future, future))
+	require.NoError(t, os.Chtimes(files.KeyFile, future, future))
+
+	// reloading is disabled, the old certificate is still served
+	cn, _ = tlsGet(t, addr, "site.test")
+	assert.Equal(t, "old", cn)
+
+	require.NoError(t, s.ReloadCertificates())
+	cn, _ = tlsGet(t, addr, "site.test")
+	assert.Equal(t, "new", cn)
+}
+
+func TestServerTLSAutomaticReload(t *testing.T) {
+	dir := t.TempDir()
+	files := writeTestCert(t, dir, "site", "old", "site.test")
+
+	_, addr := startTestServer(t, ServerOpts{
+		TLS: &TLSOptions{Certificates: []CertificateFiles{files}, ReloadInterval: time.Millisecond},
+	}, func(r *request.Request) response.Response {
+		return response.NewBaseResponse()
+	})
+
+	cn, _ := tlsGet(t, addr, "site.test")
+	assert.Equal(t, "old", cn)
+
+	writeTestCert(t, dir, "site", "new", "site.test")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(files.CertFile, future, future))
+	require.NoError(t, os.Chtimes(files.KeyFile, future, future))
+	time.Sleep(5 * time.Millisecond)
+
+	cn, _ = tlsGet(t, addr, "site.test")
+	assert.Equal(t, "new", cn)
+}
+
+func TestServerTLSConfigErrors(t *testing.T) {
+	_, err := Serve(ServerOpts{Address: "127.0.0.1:0", DisableBanner: true, TLS: &TLSOptions{}}, nil)
+	assert.Error(t, err)
+
+	_, err = Serve(ServerOpts{
+		Address:       "127.0.0.1:0",
+		DisableBanner: true,
+		TLS:           &TLSOptions{Certificates: []CertificateFiles{{CertFile: "missing.crt", KeyFile: "missing.key"}}},
+	}, nil)
+	assert.Error(t, err)
+}
+
+func TestServerTLSRequestsClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	serverCert := writeTestCert(t, dir, "server", "server", "server.test")
+	clientFiles := writeTestCert(t, dir, "client", "client", "client.test")
+	clientCert, err := tls.LoadX509KeyPair(clientFiles.CertFile, clientFiles.KeyFile)
+	require.NoError(t, err)
+
+	var peerCN string
+	_, addr := startTestServer(t, ServerOpts{
+		TLS: &TLSOptions{
+			Certificates: []CertificateFiles{serverCert},
+			ClientAuth:   tls.RequestClientCert,
+		},
+	}, func(r *request.Request) response.Response {
+		if len(r.TLS.PeerCertificates) > 0 {
+			peerCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		return response.NewBaseResponse()
+	})
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		ServerName:         "server.test",
+		InsecureSkipVerify: true,
+		Certificates:       []tls.Certificate{clientCert},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: server.test\r\n\r\n"))
+	require.NoError(t, err)
+	readResponse(t, bufio.NewReader(conn))
+	assert.Equal(t, "client", peerCN)
+}
@@ -0,0 +1,16 @@
+package validate
+
+import "errors"
+
+// ErrNotStruct is returned when the validated value isn't a struct or a pointer to one.
+var ErrNotStruct = errors.New("validated value is not a struct")
+
+// ErrUnknownRule is returned when a validate tag names a rule that isn't registered.
+var ErrUnknownRule = errors.New("unknown validation rule")
+
+// ErrInvalidRuleParam is returned when a rule parameter is invalid, e.g. `min=abc`, or when a
+// rule doesn't apply to the type of the field.
+var ErrInvalidRuleParam = errors.New("invalid validation rule parameter")
+
+// ErrInvalidRuleName is returned when registering a rule whose name can't appear in tags.
+var ErrInvalidRuleName = errors.New("invalid validation rule name")
@@ -0,0 +1,180 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var builtinRules = map[string]Rule{
+	"required": required,
+	"min":      minRule,
+	"max":      maxRule,
+	"len":      lenRule,
+	"oneof":    oneOf,
+	"email":    email,
+	"url":      urlRule,
+	"regex":    regex,
+}
+
+func required(v reflect.Value, _ string) error {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return errors.New("is required")
+		}
+	default:
+		if v.IsZero() {
+			return errors.New("is required")
+		}
+	}
+	return nil
+}
+
+// size returns the value of numbers and the length of strings, slices and maps, along with
+// the unit used in messages.
+func size(v reflect.Value) (float64, string, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), "", nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", nil
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " character", nil
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), " item", nil
+	}
+	return 0, "", fmt.Errorf("%w: %s has no size", ErrInvalidRuleParam, v.Type())
+}
+
+func sizeParam(param string) (float64, error) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a number", ErrInvalidRuleParam, param)
+	}
+	return n, nil
+}
+
+// compareSize applies a size rule, failing with message when ok returns false.
+func compareSize(v reflect.Value, param, message string, ok func(size, n float64) bool) error {
+	n, err := sizeParam(param)
+	if err != nil {
+		return err
+	}
+	s, unit, err := size(v)
+	if err != nil {
+		return err
+	}
+	if !ok(s, n) {
+		if unit != "" && n != 1 {
+			unit += "s"
+		}
+		return fmt.Errorf("%s %s%s", message, param, unit)
+	}
+	return nil
+}
+
+func minRule(v reflect.Value, param string) error {
+	return compareSize(v, param, "must be at least", func(s, n float64) bool { return s >= n })
+}
+
+func maxRule(v reflect.Value, param string) error {
+	return compareSize(v, param, "must be at most", func(s, n float64) bool { return s <= n })
+}
+
+func lenRule(v reflect.Value, param string) error {
+	return compareSize(v, param, "must be exactly", func(s, n float64) bool { return s == n })
+}
+
+func oneOf(v reflect.Value, param string) error {
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = fmt.Sprint(v.Interface())
+	default:
+		return fmt.Errorf("%w: oneof doesn't apply to %s", ErrInvalidRuleParam, v.Type())
+	}
+
+	options := strings.Fields(param)
+	for _, o := range options {
+		if s == o {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(options, ", "))
+}
+
+// stringValue returns the value of string fields.
+func stringValue(v reflect.Value, rule string) (string, error) {
+	if v.Kind() != reflect.String {
+		return "", fmt.Errorf("%w: %s doesn't apply to %s", ErrInvalidRuleParam, rule, v.Type())
+	}
+	return v.String(), nil
+}
+
+func email(v reflect.Value, _ string) error {
+	s, err := stringValue(v, "email")
+	if err != nil {
+		return err
+	}
+	// ParseAddress also accepts display names, e.g. `Sam <sam@shire.me>`
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return errors.New("must be a valid email address")
+	}
+	return nil
+}
+
+func urlRule(v reflect.Value, _ string) error {
+	s, err := stringValue(v, "url")
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return errors.New("must be a valid URL")
+	}
+	return nil
+}
+
+// regexCache holds compiled patterns, keyed by pattern.
+var regexCache sync.Map
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRuleParam, err)
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+func regex(v reflect.Value, param string) error {
+	s, err := stringValue(v, "regex")
+	if err != nil {
+		return err
+	}
+	re, err := compileRegex(param)
+	if err != nil {
+		return err
+	}
+	if !re.MatchString(s) {
+		return errors.New("must match " + param)
+	}
+	return nil
+}
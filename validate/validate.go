@@ -0,0 +1,339 @@
+// Package validate checks structs against rules declared in `validate` struct tags:
+//
+//	type Signup struct {
+//		Name    string   `json:"name" validate:"required,min=2,max=50"`
+//		Email   string   `json:"email" validate:"required,email"`
+//		Website string   `json:"website" validate:"omitempty,url"`
+//		Role    string   `json:"role" validate:"oneof=admin editor viewer"`
+//		Tags    []string `json:"tags" validate:"max=5"`
+//		Code    string   `json:"code" validate:"regex=^[A-Z]{3}-[0-9]+$"`
+//	}
+//
+// Rules are separated by commas and take their parameter after an equals sign. Since patterns
+// may contain commas, regex must be the last rule of a tag. Nested structs, pointers to structs
+// and slices of structs are validated recursively.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shravanasati/shadowfax/response"
+)
+
+// Rule checks a field value against the parameter of the rule, e.g. "5" for `min=5`.
+// The returned error message describes the failure to clients, e.g. "must be at least 5".
+// Rules return an error wrapping [ErrInvalidRuleParam] when the parameter itself is invalid.
+type Rule func(value reflect.Value, param string) error
+
+// FieldError is a field failing a rule.
+type FieldError struct {
+	// Field is the path of the field, using JSON names when the struct has json tags,
+	// e.g. `items[2].name`.
+	Field string `json:"field"`
+	// Rule is the failed rule and Param its parameter.
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+	// Message describes the failure.
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// Errors lists every rule failure of a struct.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Response renders the errors as a 422 Unprocessable Entity JSON response:
+//
+//	{"errors": [{"field": "email", "rule": "email", "message": "must be a valid email address"}]}
+func (e Errors) Response() response.Response {
+	resp, err := response.NewJSONResponse(map[string]Errors{"errors": e})
+	if err != nil {
+		return response.NewBaseResponse().WithStatusCode(response.StatusInternalServerError)
+	}
+	return resp.WithStatusCode(response.StatusUnprocessableEntity)
+}
+
+// Validator validates structs. Its rules can be extended with [Validator.RegisterRule].
+// It is safe for concurrent use.
+type Validator struct {
+	mu    sync.RWMutex
+	rules map[string]Rule
+}
+
+// New creates a Validator with the built-in rules: required, min, max, len, regex, oneof, email
+// and url. The omitempty pseudo-rule skips the other rules of a field holding its zero value.
+func New() *Validator {
+	v := &Validator{rules: make(map[string]Rule)}
+	for name, rule := range builtinRules {
+		v.rules[name] = rule
+	}
+	return v
+}
+
+// RegisterRule adds a rule, or replaces an existing one. It returns [ErrInvalidRuleName] for
+// names that can't appear in tags.
+func (v *Validator) RegisterRule(name string, rule Rule) error {
+	if name == "" || name == "omitempty" || strings.ContainsAny(name, ",= ") {
+		return fmt.Errorf("%w: %q", ErrInvalidRuleName, name)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[name] = rule
+	return nil
+}
+
+func (v *Validator) rule(name string) (Rule, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	rule, ok := v.rules[name]
+	return rule, ok
+}
+
+// Struct validates s, a struct or a pointer to one. It returns [Errors] listing every failure,
+// or an error wrapping [ErrUnknownRule] or [ErrInvalidRuleParam] if a tag is misconfigured.
+func (v *Validator) Struct(s any) error {
+	w := &walk{}
+	rv := reflect.ValueOf(s)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		w.visiting = append(w.visiting, visitOf(rv))
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T", ErrNotStruct, s)
+	}
+
+	if err := v.validateStruct(rv, "", w); err != nil {
+		return err
+	}
+	if len(w.errs) > 0 {
+		return w.errs
+	}
+	return nil
+}
+
+// walk is the state of a [Validator.Struct] call.
+type walk struct {
+	errs Errors
+	// visiting are the pointers and maps being validated, to stop at cycles
+	visiting []visit
+}
+
+// visit identifies a pointer or map. The type tells apart a struct from its first field.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+func visitOf(v reflect.Value) visit {
+	return visit{v.Pointer(), v.Type()}
+}
+
+// Default is the validator used by the package-level functions.
+var Default = New()
+
+// Struct validates s with the [Default] validator.
+func Struct(s any) error {
+	return Default.Struct(s)
+}
+
+// RegisterRule adds a rule to the [Default] validator.
+func RegisterRule(name string, rule Rule) error {
+	return Default.RegisterRule(name, rule)
+}
+
+// fieldName returns the name of a field in error paths: its JSON name, or else the key of
+// its binding tag, or else its Go name.
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "path", "query", "header", "cookie", "form"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func (v *Validator) validateStruct(rv reflect.Value, prefix string, w *walk) error {
+	t := rv.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		path := joinPath(prefix, fieldName(f))
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			path = prefix
+		}
+
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			if err := v.validateField(fv, path, tag, &w.errs); err != nil {
+				return err
+			}
+		}
+		if err := v.validateNested(fv, path, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateNested validates structs nested in a field value. Values reached again through a
+// cycle of pointers or maps are skipped, they are already being validated.
+func (v *Validator) validateNested(fv reflect.Value, path string, w *walk) error {
+	n := len(w.visiting)
+	defer func() { w.visiting = w.visiting[:n] }()
+
+	for fv.Kind() == reflect.Pointer || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return nil
+		}
+		if fv.Kind() == reflect.Pointer {
+			if !w.enter(fv) {
+				return nil
+			}
+		}
+		fv = fv.Elem()
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct:
+		if fv.Type() == reflect.TypeFor[time.Time]() {
+			return nil
+		}
+		return v.validateStruct(fv, path, w)
+	case reflect.Slice, reflect.Array:
+		if !mayHoldStruct(fv.Type().Elem()) {
+			return nil
+		}
+		for i := range fv.Len() {
+			if err := v.validateNested(fv.Index(i), fmt.Sprintf("%s[%d]", path, i), w); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if !mayHoldStruct(fv.Type().Elem()) || !w.enter(fv) {
+			return nil
+		}
+		iter := fv.MapRange()
+		for iter.Next() {
+			if err := v.validateNested(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// enter records that the pointer or map v is being validated. It reports false if it already
+// is, through a cycle.
+func (w *walk) enter(v reflect.Value) bool {
+	key := visitOf(v)
+	if slices.Contains(w.visiting, key) {
+		return false
+	}
+	w.visiting = append(w.visiting, key)
+	return true
+}
+
+// mayHoldStruct reports whether values of type t can hold structs with rules, so elements of
+// []byte or []int aren't walked one by one.
+func mayHoldStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		return t != reflect.TypeFor[time.Time]()
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return mayHoldStruct(t.Elem())
+	}
+	return false
+}
+
+// TagRule is a rule of a validate tag, e.g. `min=3` has the name "min" and the parameter "3".
+type TagRule struct {
+	Name, Param string
+}
+
+// ParseTag splits a validate tag into its rules. Everything after `regex=` is the pattern.
+func ParseTag(tag string) []TagRule {
+	var rules []TagRule
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regex=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		name, param, _ := strings.Cut(part, "=")
+		rules = append(rules, TagRule{Name: strings.TrimSpace(name), Param: param})
+	}
+	return rules
+}
+
+func (v *Validator) validateField(fv reflect.Value, path, tag string, errs *Errors) error {
+	rules := ParseTag(tag)
+
+	for _, r := range rules {
+		if r.Name == "omitempty" && fv.IsZero() {
+			return nil
+		}
+	}
+
+	// rules apply to the pointed-to value, nil pointers only fail required
+	for fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			for _, r := range rules {
+				if r.Name == "required" {
+					*errs = append(*errs, FieldError{Field: path, Rule: "required", Message: "is required"})
+				}
+			}
+			return nil
+		}
+		fv = fv.Elem()
+	}
+
+	for _, r := range rules {
+		name, param := r.Name, r.Param
+		if name == "omitempty" || name == "" {
+			continue
+		}
+		rule, ok := v.rule(name)
+		if !ok {
+			return fmt.Errorf("%w: %q on %s", ErrUnknownRule, name, path)
+		}
+		if err := rule(fv, param); err != nil {
+			if errors.Is(err, ErrInvalidRuleParam) {
+				return fmt.Errorf("%w on %s", err, path)
+			}
+			*errs = append(*errs, FieldError{Field: path, Rule: name, Param: param, Message: err.Error()})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shravanasati/shadowfax/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"len=5,regex=^[0-9,]+$"`
+}
+
+type testItem struct {
+	Name string `json:"name" validate:"required,max=10"`
+	Qty  int    `json:"qty" validate:"min=1,max=99"`
+}
+
+type Audit struct {
+	CreatedBy string `validate:"required"`
+}
+
+type testSignup struct {
+	Audit
+	Name     string            `json:"name" validate:"required,min=2,max=5"`
+	Email    string            `json:"email" validate:"required,email"`
+	Website  string            `json:"website" validate:"omitempty,url"`
+	Role     string            `json:"role" validate:"oneof=admin editor viewer"`
+	Level    int               `json:"level" validate:"oneof=1 2 3"`
+	Tags     []string          `json:"tags" validate:"max=2"`
+	Nickname *string           `json:"nickname" validate:"min=3"`
+	Age      *int              `json:"age" validate:"required"`
+	Address  testAddress       `json:"address"`
+	Billing  *testAddress      `json:"billing"`
+	Items    []testItem        `json:"items" validate:"required"`
+	Extra    map[string]string `json:"-" query:"extra" validate:"max=1"`
+	internal string            `validate:"required"`
+}
+
+func TestStruct(t *testing.T) {
+	age := 38
+	valid := testSignup{
+		Audit:   Audit{CreatedBy: "gandalf"},
+		Name:    "Sam",
+		Email:   "sam@shire.me",
+		Role:    "editor",
+		Level:   2,
+		Age:     &age,
+		Address: testAddress{City: "Hobbiton", Zip: "12345"},
+		Items:   []testItem{{Name: "rope", Qty: 1}},
+	}
+	assert.NoError(t, Struct(valid))
+	assert.NoError(t, Struct(&valid))
+
+	short := "ab"
+	invalid := testSignup{
+		Name:     "Samwise Gamgee",
+		Email:    "Sam <sam@shire.me>",
+		Website:  "shire.me",
+		Role:     "hobbit",
+		Level:    4,
+		Tags:     []string{"a", "b", "c"},
+		Nickname: &short,
+		Address:  testAddress{Zip: "1234"},
+		Billing:  &testAddress{City: "Bree", Zip: "abcde"},
+		Items:    []testItem{{Name: "rope", Qty: 1}, {Name: "", Qty: 100}},
+		Extra:    map[string]string{"a": "1", "b": "2"},
+	}
+	err := Struct(invalid)
+	var errs Errors
+	require.ErrorAs(t, err, &errs)
+
+	assert.Equal(t, Errors{
+		{Field: "CreatedBy", Rule: "required", Message: "is required"},
+		{Field: "name", Rule: "max", Param: "5", Message: "must be at most 5 characters"},
+		{Field: "email", Rule: "email", Message: "must be a valid email address"},
+		{Field: "website", Rule: "url", Message: "must be a valid URL"},
+		{Field: "role", Rule: "oneof", Param: "admin editor viewer", Message: "must be one of admin, editor, viewer"},
+		{Field: "level", Rule: "oneof", Param: "1 2 3", Message: "must be one of 1, 2, 3"},
+		{Field: "tags", Rule: "max", Param: "2", Message: "must be at most 2 items"},
+		{Field: "nickname", Rule: "min", Param: "3", Message: "must be at least 3 characters"},
+		{Field: "age", Rule: "required", Message: "is required"},
+		{Field: "address.city", Rule: "required", Message: "is required"},
+		{Field: "address.zip", Rule: "len", Param: "5", Message: "must be exactly 5 characters"},
+		{Field: "billing.zip", Rule: "regex", Param: "^[0-9,]+$", Message: "must match ^[0-9,]+$"},
+		{Field: "items[1].name", Rule: "required", Message: "is required"},
+		{Field: "items[1].qty", Rule: "max", Param: "99", Message: "must be at most 99"},
+		{Field: "extra", Rule: "max", Param: "1", Message: "must be at most 1 item"},
+	}, errs)
+	assert.Contains(t, err.Error(), "validation failed: CreatedBy is required; name must be at most 5 characters")
+}
+
+func TestStructMisconfigured(t *testing.T) {
+	assert.ErrorIs(t, Struct(42), ErrNotStruct)
+	assert.ErrorIs(t, Struct((*testItem)(nil)), ErrNotStruct)
+
+	var unknown struct {
+		Name string `validate:"required,palindrome"`
+	}
+	err := Struct(unknown)
+	assert.ErrorIs(t, err, ErrUnknownRule)
+	assert.False(t, errors.As(err, new(Errors)))
+
+	var badParam struct {
+		Name string `validate:"min=abc"`
+	}
+	assert.ErrorIs(t, Struct(badParam), ErrInvalidRuleParam)
+
+	var badType struct {
+		Count int `validate:"email"`
+	}
+	assert.ErrorIs(t, Struct(badType), ErrInvalidRuleParam)
+
+	var badRegex struct {
+		Code string `validate:"regex=[a-"`
+	}
+	assert.ErrorIs(t, Struct(badRegex), ErrInvalidRuleParam)
+}
+
+func TestRegisterRule(t *testing.T) {
+	v := New()
+	err := v.RegisterRule("even", func(value reflect.Value, _ string) error {
+		if value.Int()%2 != 0 {
+			return errors.New("must be even")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	type pair struct {
+		Count int `json:"count" validate:"even"`
+	}
+	assert.NoError(t, v.Struct(pair{Count: 4}))
+	assert.Equal(t, Errors{{Field: "count", Rule: "even", Message: "must be even"}}, v.Struct(pair{Count: 3}))
+
+	// rules are registered per validator
+	assert.ErrorIs(t, Struct(pair{Count: 3}), ErrUnknownRule)
+
+	for _, name := range []string{"", "omitempty", "a,b", "a=b"} {
+		assert.ErrorIs(t, v.RegisterRule(name, nil), ErrInvalidRuleName, name)
+	}
+}
+
+func TestErrorsResponse(t *testing.T) {
+	errs := Errors{{Field: "email", Rule: "email", Message: "must be a valid email address"}}
+	resp := errs.Response()
+	assert.Equal(t, response.StatusUnprocessableEntity, resp.GetStatusCode())
+	assert.True(t, strings.HasPrefix(resp.GetHeaders().Get("content-type"), "application/json"))
+
+	body, err := io.ReadAll(resp.GetBody())
+	require.NoError(t, err)
+	var decoded map[string][]map[string]string
+	require.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, map[string][]map[string]string{
+		"errors": {{"field": "email", "rule": "email", "message": "must be a valid email address"}},
+	}, decoded)
+}
+
+func TestParseTag(t *testing.T) {
+	assert.Equal(t, []TagRule{
+		{Name: "omitempty"},
+		{Name: "min", Param: "1"},
+		{Name: "regex", Param: "^[a-z]{1,3},[0-9]+$"},
+	}, ParseTag("omitempty,min=1,regex=^[a-z]{1,3},[0-9]+$"))
+	assert.Nil(t, ParseTag(""))
+}
+
+func TestMayHoldStruct(t *testing.T) {
+	tests := []struct {
+		value any
+		want  bool
+	}{
+		{[]byte{}, false},
+		{[]int{}, false},
+		{map[string][]string{}, false},
+		{[4]float64{}, false},
+		{[]testItem{}, true},
+		{[]*testItem{}, true},
+		{map[string][]testItem{}, true},
+		{[][2]*testAddress{}, true},
+		{[]any{}, true},
+	}
+	for _, tt := range tests {
+		typ := reflect.TypeOf(tt.value)
+		assert.Equal(t, tt.want, mayHoldStruct(typ.Elem()), typ.String())
+	}
+
+	// structs nested in slices of slices are still validated
+	nested := struct {
+		Grid [][]testItem `json:"grid"`
+	}{Grid: [][]testItem{{{Name: "rope", Qty: 1}}, {{Name: "lembas"}}}}
+	var errs Errors
+	require.ErrorAs(t, Struct(nested), &errs)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "grid[1][0].qty", errs[0].Field)
+}
+
+type testNode struct {
+	Name     string         `json:"name" validate:"required"`
+	Next     *testNode      `json:"next"`
+	Children []*testNode    `json:"children"`
+	Meta     map[string]any `json:"meta"`
+}
+
+func TestStructCycles(t *testing.T) {
+	a := &testNode{Name: "a"}
+	b := &testNode{Next: a}
+	a.Next = b
+	a.Meta = map[string]any{}
+	a.Meta["self"] = a.Meta
+
+	var errs Errors
+	require.ErrorAs(t, Struct(a), &errs)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "next.name", errs[0].Field)
+
+	// a pointer shared by siblings isn't a cycle, it is validated at each path
+	shared := &testNode{}
+	root := &testNode{Name: "root", Children: []*testNode{shared, shared}}
+	require.ErrorAs(t, Struct(root), &errs)
+	require.Len(t, errs, 2)
+	assert.Equal(t, "children[0].name", errs[0].Field)
+	assert.Equal(t, "children[1].name", errs[1].Field)
+}